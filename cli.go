@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gracew/repo-health/repohealth"
+)
+
+const usage = `usage: repo-health [command] [flags] [args]
+
+commands:
+  serve                  start the HTTP server (the default)
+  issues <owner/name>    print weekly issue metrics
+  prs <owner/name>       print weekly PR metrics
+  ci <owner/name>        print weekly CI metrics
+  user <login>           print a user's weekly activity
+  report <owner/name>... print a weekly report
+
+run repo-health <command> -h for the flags of each command`
+
+// prints the metrics served by the corresponding endpoint, e.g. `prs -weeks 4 -output csv gracew/repo-health`
+func runMetrics(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	token := flags.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token, defaults to $GITHUB_TOKEN")
+	weeks := flags.Int("weeks", 6, "number of weeks to include")
+	output := flags.String("output", "table", "table, json or csv")
+	details := flags.Bool("details", false, "print a row per issue, PR or commit instead of per week")
+	org := flags.String("org", "", "only count PRs, issues and reviews in this org (user only)")
+	flags.Parse(args)
+
+	if *token == "" || flags.NArg() != 1 {
+		target := "<owner/name>"
+		if command == "user" {
+			target = "[-org <org>] <login>"
+		}
+		fmt.Fprintf(os.Stderr, "usage: repo-health %s [-token <token>] [-weeks n] [-output table|json|csv] [-details] %s\n", command, target)
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" && *output != "csv" {
+		fmt.Fprintln(os.Stderr, "invalid output", *output)
+		os.Exit(2)
+	}
+
+	authHeader := "bearer " + *token
+	arg := flags.Arg(0)
+	var metrics interface{}
+	var err error
+	switch command {
+	case "issues":
+		metrics, err = repohealth.FetchIssueMetrics(authHeader, arg, *weeks)
+	case "prs":
+		metrics, err = repohealth.FetchPRMetrics(authHeader, arg, *weeks)
+	case "ci":
+		metrics, err = repohealth.FetchCIMetrics(authHeader, arg, *weeks)
+	case "user":
+		metrics, err = repohealth.FetchUserProfile(authHeader, arg, *org, *weeks)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	switch *output {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(metrics)
+	case "csv":
+		err = repohealth.WriteCSV(os.Stdout, metrics, *details)
+	default:
+		err = repohealth.WriteTable(os.Stdout, metrics, *details)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// prints a weekly report for the given repos, e.g. `report -format markdown gracew/repo-health`
+func runReport(config *repohealth.Config, args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	token := flags.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token, defaults to $GITHUB_TOKEN")
+	format := flags.String("format", repohealth.ReportFormatMarkdown, "markdown or html")
+	flags.Parse(args)
+
+	if *token == "" || flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: repo-health report [-token <token>] [-format markdown|html] <owner/name>...")
+		os.Exit(2)
+	}
+
+	report, err := repohealth.BuildReport("bearer "+*token, flags.Args(), time.Now())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := repohealth.WriteReport(os.Stdout, report, *format, config.Report); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gracew/repo-health/repohealth"
@@ -14,16 +16,81 @@ import (
 )
 
 func main() {
+	config, err := repohealth.LoadConfig(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(config)
+	case "report":
+		runReport(config, args)
+	case "issues", "prs", "ci", "user":
+		runMetrics(command, args)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func serve(config *repohealth.Config) {
 	router := httprouter.New()
+	router.GET("/", repohealth.ServeDashboard)
 	router.GET("/login", login)
 
+	var syncer *repohealth.Syncer
+	if len(config.Sync.Repos) > 0 {
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			log.Fatalln("GITHUB_TOKEN must be set to sync repos")
+		}
+		syncer = repohealth.NewSyncer(config.Sync, token)
+		alerter := repohealth.NewAlerter(config.Alerts, config.Health)
+		exporter := repohealth.NewExporter()
+		syncer.OnSync(alerter.Evaluate)
+		syncer.OnSync(exporter.Update)
+		go syncer.Run(nil)
+
+		// not behind requireAuthHeader, since the synced data was fetched with the server's own token
+		router.GET("/metrics", exporter.ServeMetrics)
+	}
+
 	router.GET("/repos/:owner/:name/issues", requireAuthHeader(repohealth.GetRepositoryIssues))
+	router.GET("/repos/:owner/:name/issues/open", requireAuthHeader(repohealth.GetRepositoryOpenIssues))
 
 	router.GET("/repos/:owner/:name/prs", requireAuthHeader(repohealth.GetRepositoryPRs))
+	router.GET("/repos/:owner/:name/prs/stale", requireAuthHeader(repohealth.GetRepositoryStalePRs))
 
 	router.GET("/repos/:owner/:name/ci", requireAuthHeader(repohealth.GetRepositoryCI))
 
+	router.GET("/repos/:owner/:name/health", requireAuthHeader(repohealth.GetRepositoryHealth(config)))
+
+	router.GET("/repos/:owner/:name/slos", requireAuthHeader(repohealth.GetRepositorySLOs(config)))
+
+	// served from synced data without an Authorization header, so that they can be embedded in READMEs
+	router.GET("/repos/:owner/:name/sparkline.svg", repohealth.GetRepositorySparkline(config, syncer))
+	router.GET("/repos/:owner/:name/badge.svg", repohealth.GetRepositoryBadge(config, syncer))
+
+	router.GET("/compare", requireAuthHeader(repohealth.CompareRepositories))
+
+	router.GET("/report", requireAuthHeader(repohealth.GetReport(config)))
+
 	router.GET("/users/:user", requireAuthHeader(repohealth.GetUserPRs))
+	router.GET("/users/:user/profile", requireAuthHeader(repohealth.GetUserProfile))
+
+	router.GET("/orgs/:org/issues", requireAuthHeader(repohealth.GetOrgIssues))
+
+	router.GET("/orgs/:org/prs", requireAuthHeader(repohealth.GetOrgPRs))
+
+	router.GET("/orgs/:org/ci", requireAuthHeader(repohealth.GetOrgCI))
+
+	router.GET("/orgs/:org/teams/:team/prs", requireAuthHeader(repohealth.GetTeamPRs))
 
 	if err := http.ListenAndServe(":8080", router); err != nil {
 		panic(err)
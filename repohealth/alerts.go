@@ -0,0 +1,195 @@
+package repohealth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	alertMetricHealthScore = "healthScore"
+
+	alertOpAbove = "above"
+	alertOpBelow = "below"
+
+	alertStatusFiring   = "firing"
+	alertStatusResolved = "resolved"
+
+	webhookFormatJSON  = "json"
+	webhookFormatSlack = "slack"
+)
+
+// the JSON payload posted to webhooks
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Repo      string    `json:"repo"`
+	Status    string    `json:"status"` // firing or resolved
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Op        string    `json:"op"`
+	Threshold float64   `json:"threshold"`
+	Week      string    `json:"week"`
+	At        time.Time `json:"at"`
+}
+
+// evaluates alert rules against each sync and notifies webhooks when an alert starts firing or resolves
+type Alerter struct {
+	config AlertsConfig
+	health HealthConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	firing map[string]bool // by rule name and repo, as last notified
+}
+
+func NewAlerter(config AlertsConfig, health HealthConfig) *Alerter {
+	return &Alerter{
+		config: config,
+		health: health,
+		client: &http.Client{Timeout: 30 * time.Second},
+		firing: map[string]bool{},
+	}
+}
+
+// meant to be registered with Syncer.OnSync
+func (a *Alerter) Evaluate(snapshots map[string]repoSnapshot) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, rule := range a.config.Rules {
+		repos := rule.Repos
+		if len(repos) == 0 {
+			for repo := range snapshots {
+				repos = append(repos, repo)
+			}
+		}
+
+		for _, repo := range repos {
+			snapshot, ok := snapshots[repo]
+			if !ok {
+				continue
+			}
+			week, value, ok := a.getLatestValue(rule.Metric, snapshot, now)
+			if !ok {
+				// no data last week, so leave the alert as it is
+				continue
+			}
+
+			breached := value > rule.Threshold
+			if rule.Op == alertOpBelow {
+				breached = value < rule.Threshold
+			}
+			key := rule.Name + "/" + repo
+			if breached == a.firing[key] {
+				continue
+			}
+
+			alert := Alert{
+				Rule:      rule.Name,
+				Repo:      repo,
+				Status:    alertStatusResolved,
+				Metric:    rule.Metric,
+				Value:     value,
+				Op:        rule.Op,
+				Threshold: rule.Threshold,
+				Week:      week,
+				At:        now,
+			}
+			if breached {
+				alert.Status = alertStatusFiring
+			}
+			if a.isSilenced(alert) {
+				// left as it was, so that the change is sent once the silence ends if it still holds
+				log.Printf("alert %s for %s is %s but silenced\n", rule.Name, repo, alert.Status)
+				continue
+			}
+			if a.notify(alert) {
+				a.firing[key] = breached
+			}
+		}
+	}
+}
+
+// returns the value of the metric in the latest complete week, since the current week may only have a handful of PRs
+// or issues so far. ok is false if there was no data
+func (a *Alerter) getLatestValue(metric string, snapshot repoSnapshot, now time.Time) (week string, value float64, ok bool) {
+	issues, prs, ci := snapshot.metrics()
+	latest := len(prs) - 1
+	for latest >= 0 && snapshot.Since.AddDate(0, 0, (latest+1)*7).After(now) {
+		latest--
+	}
+	if latest < 0 {
+		return "", 0, false
+	}
+	week = prs[latest].Week
+
+	value = getMetricSeries(a.health, metric, issues, prs, ci)[latest]
+	return week, value, value >= 0
+}
+
+func (a *Alerter) isSilenced(alert Alert) bool {
+	for _, silence := range a.config.Silences {
+		if (silence.Rule == "" || silence.Rule == alert.Rule) &&
+			(silence.Repo == "" || silence.Repo == alert.Repo) &&
+			!alert.At.Before(silence.Start) && alert.At.Before(silence.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns whether the alert reached at least one webhook, or there are no webhooks to send it to. otherwise it is
+// retried on the next evaluation
+func (a *Alerter) notify(alert Alert) bool {
+	sent := len(a.config.Webhooks) == 0
+	for _, webhook := range a.config.Webhooks {
+		if err := a.post(webhook, alert); err != nil {
+			log.Println(errors.Wrapf(err, "failed to send alert %s for %s", alert.Rule, alert.Repo))
+			continue
+		}
+		sent = true
+	}
+	return sent
+}
+
+func (a *Alerter) post(webhook WebhookConfig, alert Alert) error {
+	var payload interface{} = alert
+	if webhook.Format == webhookFormatSlack {
+		payload = slackMessage{Text: formatAlert(alert)}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.client.Post(webhook.URL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return errors.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// see https://api.slack.com/messaging/webhooks
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func formatAlert(alert Alert) string {
+	if alert.Status == alertStatusResolved {
+		return fmt.Sprintf(":white_check_mark: *%s* resolved for %s: %s is %g (%s %g)",
+			alert.Rule, alert.Repo, alert.Metric, alert.Value, alert.Op, alert.Threshold)
+	}
+	return fmt.Sprintf(":rotating_light: *%s* firing for %s: %s is %g (%s %g)",
+		alert.Rule, alert.Repo, alert.Metric, alert.Value, alert.Op, alert.Threshold)
+}
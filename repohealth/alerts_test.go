@@ -0,0 +1,144 @@
+package repohealth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// records the alerts posted to it
+type webhookStandIn struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func newWebhookStandIn(t *testing.T) *webhookStandIn {
+	standIn := &webhookStandIn{}
+	standIn.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+		standIn.mu.Lock()
+		standIn.alerts = append(standIn.alerts, alert)
+		standIn.mu.Unlock()
+	}))
+	return standIn
+}
+
+// returns the alerts posted since the last call
+func (s *webhookStandIn) take() []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alerts := s.alerts
+	s.alerts = nil
+	return alerts
+}
+
+// returns a snapshot of two weeks, the first complete and the second in progress, where one PR was closed in the
+// first week, merged or not, and the opposite happened in the second
+func getTestSnapshot(merged bool) map[string]repoSnapshot {
+	since := time.Now().AddDate(0, 0, -10)
+	complete := pr{CreatedAt: since.Add(time.Hour), ClosedAt: since.Add(2 * time.Hour), Merged: merged}
+	inProgress := pr{CreatedAt: since.AddDate(0, 0, 8), ClosedAt: since.AddDate(0, 0, 9), Merged: !merged}
+	return map[string]repoSnapshot{
+		"o/r": {Data: repoData{Repo: "o/r", PRs: []pr{complete, inProgress}}, Since: since, NumWeeks: 2},
+	}
+}
+
+func expectAlert(t *testing.T, alerts []Alert, status string) {
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want one %s alert", len(alerts), status)
+	}
+	if alerts[0].Status != status || alerts[0].Rule != "Low merge rate" || alerts[0].Repo != "o/r" {
+		t.Errorf("got alert %+v, want %s", alerts[0], status)
+	}
+}
+
+func expectNoAlerts(t *testing.T, alerts []Alert) {
+	if len(alerts) != 0 {
+		t.Errorf("got alerts %+v, want none", alerts)
+	}
+}
+
+func newTestAlerter(url string) *Alerter {
+	return NewAlerter(AlertsConfig{
+		Rules:    []AlertRule{{Name: "Low merge rate", Metric: indicatorMergeRate, Op: alertOpBelow, Threshold: 0.5}},
+		Webhooks: []WebhookConfig{{URL: url}},
+	}, DefaultConfig().Health)
+}
+
+func TestAlerterFiresDedupsAndResolves(t *testing.T) {
+	webhook := newWebhookStandIn(t)
+	defer webhook.server.Close()
+	alerter := newTestAlerter(webhook.server.URL)
+
+	alerter.Evaluate(getTestSnapshot(false))
+	expectAlert(t, webhook.take(), alertStatusFiring)
+
+	alerter.Evaluate(getTestSnapshot(false))
+	expectNoAlerts(t, webhook.take())
+
+	alerter.Evaluate(getTestSnapshot(true))
+	expectAlert(t, webhook.take(), alertStatusResolved)
+
+	alerter.Evaluate(getTestSnapshot(true))
+	expectNoAlerts(t, webhook.take())
+}
+
+func TestAlerterUsesLatestCompleteWeek(t *testing.T) {
+	alerter := newTestAlerter("")
+	snapshot := getTestSnapshot(true)["o/r"]
+	week, value, ok := alerter.getLatestValue(indicatorMergeRate, snapshot, time.Now())
+	if !ok || value != 1 || week != snapshot.Since.Format(dateFormat) {
+		t.Errorf("got %v for week %s, want the merge rate of the first week", value, week)
+	}
+}
+
+func TestAlerterSendsSilencedAlertsOnceSilenceEnds(t *testing.T) {
+	webhook := newWebhookStandIn(t)
+	defer webhook.server.Close()
+	alerter := newTestAlerter(webhook.server.URL)
+	now := time.Now()
+	alerter.config.Silences = []Silence{{Rule: "Low merge rate", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}
+
+	alerter.Evaluate(getTestSnapshot(false))
+	expectNoAlerts(t, webhook.take())
+
+	alerter.config.Silences[0].End = now.Add(-time.Minute)
+	alerter.Evaluate(getTestSnapshot(false))
+	expectAlert(t, webhook.take(), alertStatusFiring)
+}
+
+func TestAlerterDoesNotResolveUnsentAlerts(t *testing.T) {
+	webhook := newWebhookStandIn(t)
+	defer webhook.server.Close()
+	alerter := newTestAlerter(webhook.server.URL)
+	now := time.Now()
+	alerter.config.Silences = []Silence{{Repo: "o/r", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}
+
+	alerter.Evaluate(getTestSnapshot(false))
+	alerter.config.Silences = nil
+	alerter.Evaluate(getTestSnapshot(true))
+	expectNoAlerts(t, webhook.take())
+}
+
+func TestAlerterRetriesFailedNotifications(t *testing.T) {
+	webhook := newWebhookStandIn(t)
+	url := webhook.server.URL
+	webhook.server.Close()
+	alerter := newTestAlerter(url)
+
+	alerter.Evaluate(getTestSnapshot(false))
+
+	webhook = newWebhookStandIn(t)
+	defer webhook.server.Close()
+	alerter.config.Webhooks[0].URL = webhook.server.URL
+	alerter.Evaluate(getTestSnapshot(false))
+	expectAlert(t, webhook.take(), alertStatusFiring)
+}
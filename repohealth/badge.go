@@ -0,0 +1,295 @@
+package repohealth
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/machinebox/graphql"
+	"github.com/pkg/errors"
+)
+
+// how long fetched (rather than synced) images may be cached. metrics are weekly, so an hour is fresh enough
+const fetchedImageMaxAge = time.Hour
+
+var errMissingAuth = errors.New("repo is not synced and no Authorization header was given")
+
+// snapshots fetched for images of repos that aren't synced, kept for fetchedImageMaxAge so that every image load
+// doesn't refetch the repo. keyed by repo, weeks and token, since what a token can see differs
+var fetchedSnapshots = &snapshotCache{snapshots: map[string]repoSnapshot{}}
+
+type snapshotCache struct {
+	mu        sync.Mutex
+	snapshots map[string]repoSnapshot
+}
+
+// returns the cached snapshot if it was fetched within fetchedImageMaxAge of now
+func (c *snapshotCache) get(key string, now time.Time) (repoSnapshot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot, ok := c.snapshots[key]
+	if !ok || now.Sub(snapshot.SyncedAt) >= fetchedImageMaxAge {
+		return repoSnapshot{}, false
+	}
+	return snapshot, true
+}
+
+// caches the snapshot, dropping any that have expired
+func (c *snapshotCache) put(key string, snapshot repoSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, cached := range c.snapshots {
+		if snapshot.SyncedAt.Sub(cached.SyncedAt) >= fetchedImageMaxAge {
+			delete(c.snapshots, k)
+		}
+	}
+	c.snapshots[key] = snapshot
+}
+
+var metricLabels = map[string]string{
+	indicatorMedianReviewTime:          "median review",
+	indicatorMergeRate:                 "merge rate",
+	indicatorMedianIssueResolutionTime: "median issue resolution",
+	indicatorIssueCloseRate:            "issue close rate",
+	indicatorCIP90:                     "CI p90",
+	alertMetricHealthScore:             "health",
+}
+
+var gradeColors = map[string]string{
+	"A": "#4c1",
+	"B": "#97ca00",
+	"C": "#dfb317",
+	"D": "#fe7d37",
+	"F": "#e05d44",
+}
+
+// renders the weekly values of a metric as a sparkline, e.g. /repos/gracew/repo-health/sparkline.svg?metric=ciP90
+func GetRepositorySparkline(config *Config, syncer *Syncer) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		metric := r.URL.Query().Get("metric")
+		if _, ok := metricLabels[metric]; !ok {
+			log.Println("invalid metric parameter", metric)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		values, synced, err := getRepoMetricSeries(r, params, config, syncer, metric)
+		if err == errMissingAuth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			handleError(err, w)
+			return
+		}
+		writeSVG(w, r, renderSparkline(metricLabels[metric], values), synced, config.Sync.Interval.Duration)
+	}
+}
+
+// renders the latest value of a metric as a shields-style badge, e.g.
+// /repos/gracew/repo-health/badge.svg?metric=medianReviewTime. the label can be overridden with the label parameter
+func GetRepositoryBadge(config *Config, syncer *Syncer) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		metric := r.URL.Query().Get("metric")
+		label, ok := metricLabels[metric]
+		if !ok {
+			log.Println("invalid metric parameter", metric)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if custom := r.URL.Query().Get("label"); custom != "" {
+			label = custom
+		}
+
+		values, synced, err := getRepoMetricSeries(r, params, config, syncer, metric)
+		if err == errMissingAuth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			handleError(err, w)
+			return
+		}
+
+		// the latest week with data, since the current week may not have any yet
+		value := -1.0
+		for i := len(values) - 1; i >= 0 && value < 0; i-- {
+			value = values[i]
+		}
+		badge := renderBadge(label, formatMetricValue(metric, value), getMetricColor(config.Health, metric, value))
+		writeSVG(w, r, badge, synced, config.Sync.Interval.Duration)
+	}
+}
+
+// returns the weekly values of the metric for the repo. synced repos are served from the latest snapshot so that images
+// can be embedded where no credentials can be sent, e.g. in a README; other repos are fetched with the request's
+// Authorization header, and cached in fetchedSnapshots
+func getRepoMetricSeries(r *http.Request, params httprouter.Params, config *Config, syncer *Syncer, metric string) (values []float64, synced bool, err error) {
+	owner, name := params.ByName("owner"), params.ByName("name")
+	snapshot, synced := repoSnapshot{}, false
+	if syncer != nil {
+		snapshot, synced = syncer.getSnapshot(owner + "/" + name)
+	}
+
+	if !synced {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			return nil, false, errMissingAuth
+		}
+		numWeeks := getWeeks(r)
+		now := time.Now()
+		key := fmt.Sprintf("%s/%s %d %x", owner, name, numWeeks, sha1.Sum([]byte(authHeader)))
+		var cached bool
+		if snapshot, cached = fetchedSnapshots.get(key, now); !cached {
+			client := graphql.NewClient("https://api.github.com/graphql")
+			since := getStartDate(numWeeks)
+			data, err := getRepoData(client, authHeader, owner, name, since, prFilter{})
+			if err != nil {
+				return nil, false, err
+			}
+			snapshot = repoSnapshot{Data: data, Since: since, NumWeeks: numWeeks, SyncedAt: now}
+			fetchedSnapshots.put(key, snapshot)
+		}
+	}
+
+	issues, prs, ci := snapshot.metrics()
+	return getMetricSeries(config.Health, metric, issues, prs, ci), synced, nil
+}
+
+// synced images are shared and cached until the next sync; fetched images depend on the caller's token, so are only
+// cached privately
+func writeSVG(w http.ResponseWriter, r *http.Request, svg string, synced bool, syncInterval time.Duration) {
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum([]byte(svg)))
+	if synced {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(syncInterval.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(fetchedImageMaxAge.Seconds())))
+	}
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write([]byte(svg))
+}
+
+func formatMetricValue(metric string, value float64) string {
+	if value < 0 {
+		return "n/a"
+	}
+	switch metric {
+	case indicatorMedianReviewTime, indicatorMedianIssueResolutionTime, indicatorCIP90:
+		return formatDuration(int(value))
+	case indicatorMergeRate:
+		return fmt.Sprintf("%.0f%%", value*100)
+	case alertMetricHealthScore:
+		return fmt.Sprintf("%d (%s)", int(value), getGrade(int(value)))
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// colors by grade, using the indicator's good and bad values from the health config. indicators that aren't part of
+// the health score are blue
+func getMetricColor(config HealthConfig, metric string, value float64) string {
+	if value < 0 {
+		return "#9f9f9f"
+	}
+	if metric == alertMetricHealthScore {
+		return gradeColors[getGrade(int(value))]
+	}
+	for _, component := range config.Components {
+		if component.Indicator == metric {
+			return gradeColors[getGrade(int(math.Round(scoreIndicator(value, component.Good, component.Bad))))]
+		}
+	}
+	return "#007ec6"
+}
+
+// an approximation of the width of text in 11px Verdana, which is what shields.io badges use
+func textWidth(text string) int {
+	return len(text)*7 + 10
+}
+
+func renderBadge(label string, value string, color string) string {
+	labelWidth, valueWidth := textWidth(label), textWidth(value)
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, width, labelWidth, valueWidth, label, value, color, labelWidth/2, labelWidth+valueWidth/2)
+}
+
+// draws the values as a line, leaving gaps for weeks without data
+func renderSparkline(title string, values []float64) string {
+	const width, height, padding = 100, 20, 2
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, value := range values {
+		if value >= 0 {
+			min, max = math.Min(min, value), math.Max(max, value)
+		}
+	}
+	x := func(i int) float64 {
+		if len(values) < 2 {
+			return width / 2
+		}
+		return padding + float64(i)*(width-2*padding)/float64(len(values)-1)
+	}
+	y := func(value float64) float64 {
+		if max == min {
+			return height / 2
+		}
+		return height - padding - (value-min)/(max-min)*(height-2*padding)
+	}
+
+	// runs of consecutive weeks with data
+	var lines [][]string
+	var points []string
+	for i, value := range values {
+		if value < 0 {
+			if len(points) > 0 {
+				lines = append(lines, points)
+			}
+			points = nil
+			continue
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(value)))
+	}
+	if len(points) > 0 {
+		lines = append(lines, points)
+	}
+
+	var shapes []string
+	for _, line := range lines {
+		if len(line) == 1 {
+			// a zero-length line, which the round cap draws as a dot
+			line = append(line, line[0])
+		}
+		shapes = append(shapes, fmt.Sprintf(`<polyline points="%s" fill="none" stroke="#0366d6" stroke-width="1.5" stroke-linecap="round"/>`, strings.Join(line, " ")))
+	}
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] >= 0 {
+			shapes = append(shapes, fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="2" fill="#0366d6"/>`, x(i), y(values[i])))
+			break
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">
+<title>%s</title>
+%s
+</svg>
+`, width, height, width, height, html.EscapeString(title), strings.Join(shapes, "\n"))
+}
@@ -0,0 +1,131 @@
+package repohealth
+
+import (
+	"sort"
+	"time"
+
+	"github.com/machinebox/graphql"
+)
+
+type RepoComparison struct {
+	Repos []ComparedRepo `json:"repos"`
+	// repo names for each summary metric, ordered from best to worst. repos without data for a metric come last
+	Rankings map[string][]string `json:"rankings"`
+}
+
+type ComparedRepo struct {
+	Repo    string               `json:"repo"`
+	Issues  []WeeklyIssueMetrics `json:"issues"`
+	PRs     []WeeklyPRMetrics    `json:"prs"`
+	CI      []WeeklyCIMetrics    `json:"ci"`
+	Summary RepoSummary          `json:"summary"`
+}
+
+// over the whole window
+type RepoSummary struct {
+	MedianReviewTime int     `json:"medianReviewTime"` // in sec, -1 if no PRs were reviewed
+	MergeRate        float64 `json:"mergeRate"`        // fraction of closed PRs that were merged, -1 if none were closed
+	CIP90            int     `json:"ciP90"`            // in sec, -1 if no checks ran
+}
+
+// the fetched data for one repo being compared
+type repoData struct {
+	Repo    string
+	Issues  []issue
+	NumOpen int
+	PRs     []pr
+	CIPRs   []pr // fetched with prWithCIMetadataFragment
+}
+
+func getRepoData(client *graphql.Client, authHeader string, owner string, name string, since time.Time, filter prFilter) (repoData, error) {
+	repo := repoData{Repo: owner + "/" + name}
+
+	issues, numOpen, err := getIssuesUpdatedSince(client, authHeader, owner, name, since)
+	if err != nil {
+		return repo, err
+	}
+	prs, err := getRepoPRsCreatedSince(client, authHeader, owner, name, since, prFragment, filter)
+	if err != nil {
+		return repo, err
+	}
+	ciPRs, err := getRepoPRsCreatedSince(client, authHeader, owner, name, since, prWithCIMetadataFragment, filter)
+	if err != nil {
+		return repo, err
+	}
+	repo.Issues, repo.NumOpen, repo.PRs, repo.CIPRs = issues, numOpen, prs, ciPRs
+	return repo, nil
+}
+
+func GetRepoSummary(prMetrics []WeeklyPRMetrics, ciMetrics []WeeklyCIMetrics) RepoSummary {
+	var reviewTimes, ciDurations []int
+	numMerged, numClosed := 0, 0
+	for _, week := range prMetrics {
+		numMerged += week.NumMerged
+		numClosed += week.NumMerged + week.NumRejected
+		for _, details := range week.Details {
+			reviewTimes = appendIfSet(reviewTimes, details.ReviewTime)
+		}
+	}
+	for _, week := range ciMetrics {
+		ciDurations = append(ciDurations, getCIDurations(week.Details)...)
+	}
+
+	mergeRate := -1.0
+	if numClosed > 0 {
+		mergeRate = float64(numMerged) / float64(numClosed)
+	}
+	return RepoSummary{
+		MedianReviewTime: percentile(reviewTimes, 50),
+		MergeRate:        mergeRate,
+		CIP90:            percentile(ciDurations, 90),
+	}
+}
+
+func GetRepoComparison(repos []repoData, since time.Time, numWeeks int) RepoComparison {
+	comparison := RepoComparison{Repos: []ComparedRepo{}}
+	for _, repo := range repos {
+		prMetrics := GetPRScore(repo.PRs, since, numWeeks)
+		ciMetrics := GetCIScore(repo.CIPRs, since, numWeeks)
+		comparison.Repos = append(comparison.Repos, ComparedRepo{
+			Repo:    repo.Repo,
+			Issues:  GetIssueScore(repo.Issues, repo.NumOpen, since, numWeeks, false),
+			PRs:     prMetrics,
+			CI:      ciMetrics,
+			Summary: GetRepoSummary(prMetrics, ciMetrics),
+		})
+	}
+
+	comparison.Rankings = map[string][]string{
+		"medianReviewTime": rankRepos(comparison.Repos, func(s RepoSummary) float64 {
+			return float64(s.MedianReviewTime)
+		}, false),
+		"mergeRate": rankRepos(comparison.Repos, func(s RepoSummary) float64 {
+			return s.MergeRate
+		}, true),
+		"ciP90": rankRepos(comparison.Repos, func(s RepoSummary) float64 {
+			return float64(s.CIP90)
+		}, false),
+	}
+	return comparison
+}
+
+// orders repos by the summary metric, where negative values mean there was no data
+func rankRepos(repos []ComparedRepo, metric func(RepoSummary) float64, higherIsBetter bool) []string {
+	ranked := append([]ComparedRepo(nil), repos...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := metric(ranked[i].Summary), metric(ranked[j].Summary)
+		if a < 0 || b < 0 {
+			return b < 0 && a >= 0
+		}
+		if higherIsBetter {
+			return a > b
+		}
+		return a < b
+	})
+
+	names := []string{}
+	for _, repo := range ranked {
+		names = append(names, repo.Repo)
+	}
+	return names
+}
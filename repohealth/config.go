@@ -0,0 +1,237 @@
+package repohealth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	Health HealthConfig `json:"health"`
+	SLOs   []SLOConfig  `json:"slos"`
+	Sync   SyncConfig   `json:"sync"`
+	Alerts AlertsConfig `json:"alerts"`
+	Report ReportConfig `json:"report"`
+}
+
+// paths to templates that replace the default report templates, see report.go for the data they are given
+type ReportConfig struct {
+	MarkdownTemplate string `json:"markdownTemplate"`
+	HTMLTemplate     string `json:"htmlTemplate"`
+}
+
+// repos that are fetched in the background with the server's own token, see Syncer
+type SyncConfig struct {
+	Repos    []string `json:"repos"` // owner/name
+	Interval Duration `json:"interval"`
+	Weeks    int      `json:"weeks"`
+	Endpoint string   `json:"endpoint"` // GitHub GraphQL API
+}
+
+type AlertsConfig struct {
+	Rules    []AlertRule     `json:"rules"`
+	Webhooks []WebhookConfig `json:"webhooks"`
+	Silences []Silence       `json:"silences"`
+}
+
+// fires when the latest complete week's value of the metric is above or below the threshold
+type AlertRule struct {
+	Name      string   `json:"name"`
+	Repos     []string `json:"repos"`  // empty for every synced repo
+	Metric    string   `json:"metric"` // a health indicator, or healthScore
+	Op        string   `json:"op"`     // above or below
+	Threshold float64  `json:"threshold"`
+}
+
+type WebhookConfig struct {
+	URL    string `json:"url"`
+	Format string `json:"format"` // json (the default) or slack
+}
+
+// suppresses notifications for matching alerts between Start and End. an empty rule or repo matches any
+type Silence struct {
+	Rule  string    `json:"rule"`
+	Repo  string    `json:"repo"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type HealthConfig struct {
+	Components []HealthComponentConfig `json:"components"`
+}
+
+// an indicator scores 100 at or better than Good and 0 at or worse than Bad, linearly in between. Good may be larger
+// or smaller than Bad depending on whether higher values are better
+type HealthComponentConfig struct {
+	Indicator string  `json:"indicator"` // see indicators
+	Weight    float64 `json:"weight"`
+	Good      float64 `json:"good"`
+	Bad       float64 `json:"bad"`
+}
+
+// e.g. 90% of PRs get a first review within a day over the last 4 weeks
+type SLOConfig struct {
+	Name       string   `json:"name"`
+	Metric     string   `json:"metric"` // see sloMetrics
+	Threshold  Duration `json:"threshold"`
+	Percentile float64  `json:"percentile"` // percentage of items that must meet the threshold
+	Window     int      `json:"window"`     // in weeks
+	// if set, weekends are left out when measuring durations, so a threshold of 24h is one business day
+	BusinessTime bool `json:"businessTime"`
+}
+
+// a time.Duration that is written in JSON as a string like "15m"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Health: HealthConfig{
+			Components: []HealthComponentConfig{
+				{Indicator: indicatorMedianReviewTime, Weight: 0.25, Good: 4 * 60 * 60, Bad: 3 * 24 * 60 * 60},
+				{Indicator: indicatorMergeRate, Weight: 0.15, Good: 0.9, Bad: 0.5},
+				{Indicator: indicatorMedianIssueResolutionTime, Weight: 0.2, Good: 3 * 24 * 60 * 60, Bad: 30 * 24 * 60 * 60},
+				{Indicator: indicatorIssueCloseRate, Weight: 0.2, Good: 1, Bad: 0.5},
+				{Indicator: indicatorCIP90, Weight: 0.2, Good: 10 * 60, Bad: 60 * 60},
+			},
+		},
+		SLOs: []SLOConfig{
+			{
+				Name:         "First review within 1 business day",
+				Metric:       sloMetricPRFirstReview,
+				Threshold:    Duration{24 * time.Hour},
+				Percentile:   90,
+				Window:       4,
+				BusinessTime: true,
+			},
+			{
+				Name:       "CI p90 under 15 minutes",
+				Metric:     sloMetricCIDuration,
+				Threshold:  Duration{15 * time.Minute},
+				Percentile: 90,
+				Window:     4,
+			},
+		},
+		Sync: SyncConfig{
+			Interval: Duration{15 * time.Minute},
+			Weeks:    6,
+			Endpoint: "https://api.github.com/graphql",
+		},
+	}
+}
+
+// loads the JSON config at path, with any section not set there taken from DefaultConfig. an empty path returns the
+// default config
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		return DefaultConfig(), nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config")
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrap(err, "failed to parse config")
+	}
+	config.setDefaults()
+	if err := config.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+	return config, nil
+}
+
+func (c *Config) setDefaults() {
+	defaults := DefaultConfig()
+	if len(c.Health.Components) == 0 {
+		c.Health = defaults.Health
+	}
+	if c.SLOs == nil {
+		c.SLOs = defaults.SLOs
+	}
+	if c.Sync.Interval.Duration == 0 {
+		c.Sync.Interval = defaults.Sync.Interval
+	}
+	if c.Sync.Weeks == 0 {
+		c.Sync.Weeks = defaults.Sync.Weeks
+	}
+	if c.Sync.Endpoint == "" {
+		c.Sync.Endpoint = defaults.Sync.Endpoint
+	}
+}
+
+func (c *Config) validate() error {
+	for _, component := range c.Health.Components {
+		if _, ok := indicators[component.Indicator]; !ok {
+			return errors.Errorf("unknown health indicator %q", component.Indicator)
+		}
+		if component.Weight <= 0 {
+			return errors.Errorf("weight of health indicator %q must be positive", component.Indicator)
+		}
+		if component.Good == component.Bad {
+			return errors.Errorf("good and bad thresholds of health indicator %q must differ", component.Indicator)
+		}
+	}
+	for _, slo := range c.SLOs {
+		if _, ok := sloMetrics[slo.Metric]; !ok {
+			return errors.Errorf("unknown metric %q for SLO %q", slo.Metric, slo.Name)
+		}
+		if slo.Percentile <= 0 || slo.Percentile > 100 {
+			return errors.Errorf("percentile of SLO %q must be in (0, 100]", slo.Name)
+		}
+		if slo.Window <= 0 {
+			return errors.Errorf("window of SLO %q must be at least one week", slo.Name)
+		}
+	}
+	if c.Sync.Interval.Duration <= 0 {
+		return errors.New("sync interval must be positive")
+	}
+	syncedRepos := map[string]bool{}
+	for _, repo := range c.Sync.Repos {
+		if _, _, ok := splitRepo(repo); !ok {
+			return errors.Errorf("invalid sync repo %q, expected owner/name", repo)
+		}
+		syncedRepos[repo] = true
+	}
+	for _, rule := range c.Alerts.Rules {
+		if _, ok := indicators[rule.Metric]; !ok && rule.Metric != alertMetricHealthScore {
+			return errors.Errorf("unknown metric %q for alert %q", rule.Metric, rule.Name)
+		}
+		if rule.Op != alertOpAbove && rule.Op != alertOpBelow {
+			return errors.Errorf("op of alert %q must be %s or %s", rule.Name, alertOpAbove, alertOpBelow)
+		}
+		// alerts are only evaluated against synced repos
+		for _, repo := range rule.Repos {
+			if !syncedRepos[repo] {
+				return errors.Errorf("repo %q of alert %q is not synced", repo, rule.Name)
+			}
+		}
+	}
+	for _, webhook := range c.Alerts.Webhooks {
+		if webhook.Format != "" && webhook.Format != webhookFormatJSON && webhook.Format != webhookFormatSlack {
+			return errors.Errorf("unknown format %q for webhook %s", webhook.Format, webhook.URL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package repohealth
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// serves a single-page dashboard that charts the /repos/... and /users/... endpoints. it is not behind
+// requireAuthHeader: the page asks for a GitHub token and sends it with each request it makes
+func ServeDashboard(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+//go:embed static/dashboard.html
+var dashboardHTML []byte
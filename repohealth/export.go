@@ -0,0 +1,290 @@
+package repohealth
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// a flattened row of a response, with columns named by the JSON path to each value, e.g. "cycleTime.pickup"
+type row []cell
+
+type cell struct {
+	column string
+	value  interface{}
+}
+
+// writes v in the format asked for by the format query parameter or Accept header, defaulting to JSON. for CSV and
+// NDJSON, nested series are flattened into one row per week, or one row per item in each week's details if the rows
+// query parameter is "details". an unsupported format query parameter is a bad request
+func writeResponse(w http.ResponseWriter, r *http.Request, v interface{}) {
+	format, ok := getFormat(r)
+	if !ok {
+		log.Println("invalid format parameter", format)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	details := r.URL.Query().Get("rows") == "details"
+	var err error
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = WriteCSV(w, v, details)
+	case formatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = WriteNDJSON(w, v, details)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(v)
+	}
+	if err != nil {
+		// too late to change the status code
+		log.Println("failed to write response:", err)
+	}
+}
+
+// ok is false if the format query parameter isn't a supported format
+func getFormat(r *http.Request) (format string, ok bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case formatJSON, formatCSV, formatNDJSON:
+			return format, true
+		}
+		return format, false
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return formatCSV, true
+	case strings.Contains(accept, "application/x-ndjson"), strings.Contains(accept, "application/ndjson"):
+		return formatNDJSON, true
+	}
+	return formatJSON, true
+}
+
+func WriteCSV(w io.Writer, v interface{}, details bool) error {
+	rows := getRows(reflect.ValueOf(v), "", details, false)
+	columns := getColumns(rows)
+	index := map[string]int{}
+	for i, column := range columns {
+		index[column] = i
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for _, cell := range row {
+			record[index[cell.column]] = formatCell(cell.value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writes the same rows as WriteCSV as aligned columns, for reading in a terminal
+func WriteTable(w io.Writer, v interface{}, details bool) error {
+	rows := getRows(reflect.ValueOf(v), "", details, false)
+	columns := getColumns(rows)
+	index := map[string]int{}
+	for i, column := range columns {
+		index[column] = i
+	}
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(columns, "\t"))
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for _, cell := range row {
+			record[index[cell.column]] = formatCell(cell.value)
+		}
+		fmt.Fprintln(writer, strings.Join(record, "\t"))
+	}
+	return writer.Flush()
+}
+
+// writes one JSON object per row, flushing after each so that clients can start reading right away
+func WriteNDJSON(w io.Writer, v interface{}, details bool) error {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, row := range getRows(reflect.ValueOf(v), "", details, false) {
+		object := map[string]interface{}{}
+		for _, cell := range row {
+			object[cell.column] = cell.value
+		}
+		if err := encoder.Encode(object); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// returns the union of the columns of every row, in the order they first appear
+func getColumns(rows []row) []string {
+	seen := map[string]bool{}
+	columns := []string{}
+	for _, row := range rows {
+		for _, cell := range row {
+			if !seen[cell.column] {
+				seen[cell.column] = true
+				columns = append(columns, cell.column)
+			}
+		}
+	}
+	return columns
+}
+
+// flattens v into rows. slices of structs become one row per element, with the scalar fields of the enclosing
+// structs repeated on each. details slices are skipped unless details is set. each detail is a single row, so any
+// slices of structs within it are kept in a single cell rather than being expanded
+func getRows(v reflect.Value, prefix string, details bool, leaf bool) []row {
+	v = indirect(v)
+	switch {
+	case !v.IsValid():
+		return nil
+	case isSeries(v):
+		var rows []row
+		for i := 0; i < v.Len(); i++ {
+			rows = append(rows, getRows(v.Index(i), prefix, details, leaf)...)
+		}
+		return rows
+	case v.Kind() == reflect.Struct && !isScalar(v):
+		var scalars row
+		var children []series
+		collectFields(v, prefix, details, leaf, &scalars, &children)
+		if len(children) == 0 {
+			return []row{scalars}
+		}
+
+		var rows []row
+		for _, s := range children {
+			for _, child := range getRows(s.value, s.prefix, details, s.leaf) {
+				rows = append(rows, append(append(row{}, scalars...), child...))
+			}
+		}
+		return rows
+	default:
+		return []row{{{column: strings.TrimSuffix(prefix, "."), value: v.Interface()}}}
+	}
+}
+
+// a slice of structs to expand into rows
+type series struct {
+	value  reflect.Value
+	prefix string
+	leaf   bool // whether the elements are details
+}
+
+// splits the fields of a struct into scalar cells, flattening nested structs and maps, and series to descend into
+func collectFields(v reflect.Value, prefix string, details bool, leaf bool, scalars *row, children *[]series) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value := indirect(v.Field(i))
+		column := prefix + name
+
+		switch {
+		case !value.IsValid():
+			*scalars = append(*scalars, cell{column: column, value: nil})
+		case field.Anonymous && value.Kind() == reflect.Struct:
+			collectFields(value, prefix, details, leaf, scalars, children)
+		case isSeries(value) && !leaf:
+			if name != "details" || details {
+				*children = append(*children, series{value: value, prefix: column + ".", leaf: name == "details"})
+			}
+		case value.Kind() == reflect.Struct && !isScalar(value):
+			collectFields(value, column+".", details, leaf, scalars, children)
+		case value.Kind() == reflect.Map:
+			keys := value.MapKeys()
+			sort.Slice(keys, func(a, b int) bool {
+				return fmt.Sprint(keys[a].Interface()) < fmt.Sprint(keys[b].Interface())
+			})
+			for _, key := range keys {
+				*scalars = append(*scalars, cell{column: fmt.Sprintf("%s.%v", column, key.Interface()), value: value.MapIndex(key).Interface()})
+			}
+		default:
+			*scalars = append(*scalars, cell{column: column, value: value.Interface()})
+		}
+	}
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// slices of structs are flattened into rows, while slices of scalars are kept in a single cell
+func isSeries(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct && elem != reflect.TypeOf(time.Time{})
+}
+
+func isScalar(v reflect.Value) bool {
+	return v.Type() == reflect.TypeOf(time.Time{})
+}
+
+func formatCell(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	case []string:
+		return strings.Join(value, ";")
+	}
+	if v := reflect.ValueOf(value); isSeries(v) {
+		// each element as JSON, e.g. the PRs that fixed an issue
+		elements := make([]string, v.Len())
+		for i := range elements {
+			data, err := json.Marshal(v.Index(i).Interface())
+			if err != nil {
+				return fmt.Sprint(value)
+			}
+			elements[i] = string(data)
+		}
+		return strings.Join(elements, ";")
+	}
+	return fmt.Sprint(value)
+}
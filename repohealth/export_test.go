@@ -0,0 +1,126 @@
+package repohealth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getTestIssueMetrics() []WeeklyIssueMetrics {
+	return []WeeklyIssueMetrics{
+		{
+			Week:      "2026-10-04",
+			NumOpen:   3,
+			NumClosed: 2,
+			Details: []IssueDetails{
+				{Number: 1, Labels: []string{"bug", "ui"}},
+				{Number: 2, FixedBy: []LinkedPR{{Number: 10, URL: "https://github.com/o/r/pull/10"}}},
+				{Number: 3, FixedBy: []LinkedPR{
+					{Number: 11, URL: "https://github.com/o/r/pull/11"},
+					{Number: 12, URL: "https://github.com/o/r/pull/12"},
+				}},
+			},
+		},
+		{Week: "2026-10-11", NumOpen: 1},
+	}
+}
+
+func readCSV(t *testing.T, data []byte) []map[string]string {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestWriteCSVSummary(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, getTestIssueMetrics(), false); err != nil {
+		t.Fatal(err)
+	}
+	rows := readCSV(t, buf.Bytes())
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want one per week", len(rows))
+	}
+	if rows[0]["week"] != "2026-10-04" || rows[0]["opened"] != "3" || rows[1]["week"] != "2026-10-11" {
+		t.Errorf("unexpected rows %v", rows)
+	}
+	if _, ok := rows[0]["details.number"]; ok {
+		t.Error("summary rows should not include details")
+	}
+}
+
+func TestWriteCSVDetails(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, getTestIssueMetrics(), true); err != nil {
+		t.Fatal(err)
+	}
+	rows := readCSV(t, buf.Bytes())
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want one per issue", len(rows))
+	}
+	for i, row := range rows {
+		if want := []string{"1", "2", "3"}[i]; row["details.number"] != want {
+			t.Errorf("row %d has number %s, want %s", i, row["details.number"], want)
+		}
+		if row["week"] != "2026-10-04" {
+			t.Errorf("row %d has week %s", i, row["week"])
+		}
+	}
+	if rows[0]["details.fixedBy"] != "" {
+		t.Errorf("got fixedBy %q for an issue without fixes", rows[0]["details.fixedBy"])
+	}
+	if rows[0]["details.labels"] != "bug;ui" {
+		t.Errorf("got labels %q", rows[0]["details.labels"])
+	}
+	if fixedBy := rows[2]["details.fixedBy"]; !strings.Contains(fixedBy, "pull/11") || !strings.Contains(fixedBy, "pull/12") {
+		t.Errorf("got fixedBy %q, want both PRs", fixedBy)
+	}
+}
+
+func TestWriteNDJSONDetails(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, getTestIssueMetrics(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want one per issue", len(lines))
+	}
+	if number := lines[0]["details.number"]; number != 1.0 {
+		t.Errorf("got number %v", number)
+	}
+	if fixedBy, ok := lines[2]["details.fixedBy"].([]interface{}); !ok || len(fixedBy) != 2 {
+		t.Errorf("got fixedBy %v, want both PRs", lines[2]["details.fixedBy"])
+	}
+}
+
+func TestWriteResponseRejectsUnknownFormat(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeResponse(recorder, httptest.NewRequest("GET", "/repos/o/r/issues?format=xml", nil), getTestIssueMetrics())
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,84 @@
+package repohealth
+
+import (
+	"github.com/machinebox/graphql"
+	"github.com/pkg/errors"
+)
+
+// fetches and scores a repo's issues over the last numWeeks weeks, for callers outside of the HTTP handlers such as
+// the CLI. repo is owner/name
+func FetchIssueMetrics(authHeader string, repo string, numWeeks int) ([]WeeklyIssueMetrics, error) {
+	owner, name, ok := splitRepo(repo)
+	if !ok {
+		return nil, errors.Errorf("invalid repo %q, expected owner/name", repo)
+	}
+	client := graphql.NewClient("https://api.github.com/graphql")
+	since := getStartDate(numWeeks)
+
+	issues, numOpen, err := getIssuesUpdatedSince(client, authHeader, owner, name, since)
+	if err != nil {
+		return nil, err
+	}
+	return GetIssueScore(issues, numOpen, since, numWeeks, false), nil
+}
+
+// fetches and scores PRs against a repo's default branch over the last numWeeks weeks. repo is owner/name
+func FetchPRMetrics(authHeader string, repo string, numWeeks int) ([]WeeklyPRMetrics, error) {
+	owner, name, ok := splitRepo(repo)
+	if !ok {
+		return nil, errors.Errorf("invalid repo %q, expected owner/name", repo)
+	}
+	client := graphql.NewClient("https://api.github.com/graphql")
+	since := getStartDate(numWeeks)
+
+	prs, err := getRepoPRsCreatedSince(client, authHeader, owner, name, since, prFragment, prFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return GetPRScore(prs, since, numWeeks), nil
+}
+
+// fetches and scores CI on PRs against a repo's default branch over the last numWeeks weeks. repo is owner/name
+func FetchCIMetrics(authHeader string, repo string, numWeeks int) ([]WeeklyCIMetrics, error) {
+	owner, name, ok := splitRepo(repo)
+	if !ok {
+		return nil, errors.Errorf("invalid repo %q, expected owner/name", repo)
+	}
+	client := graphql.NewClient("https://api.github.com/graphql")
+	since := getStartDate(numWeeks)
+
+	prs, err := getRepoPRsCreatedSince(client, authHeader, owner, name, since, prWithCIMetadataFragment, prFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return GetCIScore(prs, since, numWeeks), nil
+}
+
+// fetches and scores a user's activity over the last numWeeks weeks. if org is set, only PRs, issues and reviews in
+// that org's repos are counted
+func FetchUserProfile(authHeader string, user string, org string, numWeeks int) (UserProfile, error) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+	since := getStartDate(numWeeks)
+
+	var orgID string
+	if org != "" {
+		var err error
+		if orgID, err = getOrgID(client, authHeader, org); err != nil {
+			return UserProfile{}, err
+		}
+	}
+
+	prs, err := getUserPRsCreatedSince(client, authHeader, user, since)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	reviews, err := getUserReviewsSince(client, authHeader, user, orgID, since)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	issues, err := getUserIssuesCreatedSince(client, authHeader, user, since)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	return GetUserScore(org, prs, reviews, issues, since, numWeeks), nil
+}
@@ -2,6 +2,10 @@ package repohealth
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/machinebox/graphql"
@@ -10,6 +14,9 @@ import (
 
 type issueDatesResponse struct {
 	Repository struct {
+		OpenIssues struct {
+			TotalCount int
+		}
 		Issues struct {
 			Nodes    []issue
 			PageInfo pageInfo
@@ -18,12 +25,62 @@ type issueDatesResponse struct {
 }
 
 type issue struct {
-	Number    int
-	Title     string
-	URL       string
-	State     string
-	CreatedAt time.Time
-	ClosedAt  time.Time
+	Number      int
+	Title       string
+	URL         string
+	State       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ClosedAt    time.Time
+	StateReason string // COMPLETED, NOT_PLANNED, DUPLICATE or REOPENED
+	Assignees   struct {
+		Nodes []struct {
+			Login string
+		}
+	}
+	Labels struct {
+		Nodes []label
+	}
+	Author struct {
+		Login string
+	}
+	AuthorAssociation string
+	Comments          struct {
+		Nodes    []timelineItem
+		PageInfo pageInfo
+	}
+	FirstLabel struct {
+		Nodes []timelineItem
+	}
+	FirstAssignment struct {
+		Nodes []timelineItem
+	}
+	ReopenedEvents struct {
+		Nodes []timelineItem
+	}
+	Repository struct {
+		NameWithOwner string
+		Owner         struct {
+			Login string
+		}
+	}
+	ClosedByPullRequestsReferences struct {
+		Nodes []struct {
+			Number   int
+			URL      string
+			Merged   bool
+			MergedAt time.Time
+		}
+	}
+}
+
+// only the fields needed by the item types that are requested are filled in
+type timelineItem struct {
+	CreatedAt         time.Time
+	AuthorAssociation string
+	Author            struct {
+		Login string
+	}
 }
 
 type pageInfo struct {
@@ -31,31 +88,198 @@ type pageInfo struct {
 	HasNextPage bool
 }
 
-func getIssuesCreatedSince(client *graphql.Client, authHeader string, owner string, name string, since time.Time) ([]issue, error) {
+// returns issues that were updated (which includes being opened or closed) since the given date, along with the
+// number of issues that are currently open
+func getIssuesUpdatedSince(client *graphql.Client, authHeader string, owner string, name string, since time.Time) ([]issue, int, error) {
 	req := graphql.NewRequest(`
-		query ($owner: String!, $name: String!, $pageSize: Int!, $after: String) {
+		query ($owner: String!, $name: String!, $pageSize: Int!, $after: String, $since: DateTime!) {
 			repository(owner: $owner, name: $name) {
-		 		issues(first: $pageSize, after: $after, orderBy: {field: CREATED_AT, direction: DESC}) {
-					nodes {
-						number
-						title
-						url
-						state
+				openIssues: issues(states: OPEN) {
+					totalCount
+				}
+		 		issues(first: $pageSize, after: $after, filterBy: {since: $since}, orderBy: {field: UPDATED_AT, direction: DESC}) {
+					...issueFields
+				}
+			}
+	  	}
+	` + issueFragment)
+	req.Var("owner", owner)
+	req.Var("name", name)
+	req.Var("pageSize", pageSize)
+	req.Var("after", nil)
+	req.Var("since", since)
+	req.Header.Set("Authorization", authHeader)
+
+	var issues []issue
+	numOpen := 0
+	getNextPage := true
+	for getNextPage {
+		var res issueDatesResponse
+		if err := client.Run(context.Background(), req, &res); err != nil {
+			return nil, 0, errors.Wrap(err, "failed to fetch repo issues")
+		}
+		numOpen = res.Repository.OpenIssues.TotalCount
+		issues = append(issues, res.Repository.Issues.Nodes...)
+		getNextPage = res.Repository.Issues.PageInfo.HasNextPage
+		req.Var("after", res.Repository.Issues.PageInfo.EndCursor)
+	}
+
+	if err := getMaintainerResponses(client, authHeader, owner, name, issues, since); err != nil {
+		return nil, 0, err
+	}
+	return issues, numOpen, nil
+}
+
+const issueFragment = `
+	fragment issueFields on IssueConnection {
+		nodes {
+			number
+			title
+			url
+			state
+			createdAt
+			updatedAt
+			closedAt
+			stateReason
+			assignees(first: 10) {
+				nodes {
+					login
+				}
+			}
+			labels(first: 20) {
+				nodes {
+					name
+				}
+			}
+			author {
+				login
+			}
+			authorAssociation
+			comments: timelineItems(first: 20, itemTypes: [ISSUE_COMMENT]) {
+				...commentFields
+			}
+			firstLabel: timelineItems(first: 1, itemTypes: [LABELED_EVENT]) {
+				nodes {
+					... on LabeledEvent {
 						createdAt
-						closedAt
 					}
-					pageInfo {
-						endCursor
-						hasNextPage
+				}
+			}
+			firstAssignment: timelineItems(first: 1, itemTypes: [ASSIGNED_EVENT]) {
+				nodes {
+					... on AssignedEvent {
+						createdAt
 					}
 				}
 			}
-	  	}
-	`)
+			reopenedEvents: timelineItems(last: 10, itemTypes: [REOPENED_EVENT]) {
+				nodes {
+					... on ReopenedEvent {
+						createdAt
+					}
+				}
+			}
+			closedByPullRequestsReferences(first: 5) {
+				nodes {
+					number
+					url
+					merged
+					mergedAt
+				}
+			}
+		}
+		pageInfo {
+			endCursor
+			hasNextPage
+		}
+	}
+` + commentFragment
+
+const commentFragment = `
+	fragment commentFields on IssueTimelineItemsConnection {
+		nodes {
+			... on IssueComment {
+				createdAt
+				authorAssociation
+				author {
+					login
+				}
+			}
+		}
+		pageInfo {
+			endCursor
+			hasNextPage
+		}
+	}
+`
+
+type issueCommentsResponse struct {
+	Repository struct {
+		Issue struct {
+			Comments struct {
+				Nodes    []timelineItem
+				PageInfo pageInfo
+			}
+		}
+	}
+}
+
+// fetches further pages of comments for issues created since the given date without a maintainer response in the
+// comments fetched so far, until one is found. older issues are left as is, since triage is only measured for issues
+// opened in the requested weeks
+func getMaintainerResponses(client *graphql.Client, authHeader string, owner string, name string, issues []issue, createdSince time.Time) error {
+	return forEachParallel(len(issues), maxParallelism, func(i int) error {
+		issue := &issues[i]
+		if issue.CreatedAt.Before(createdSince) {
+			return nil
+		}
+		for getFirstMaintainerResponse(*issue) == nil && issue.Comments.PageInfo.HasNextPage {
+			req := graphql.NewRequest(`
+				query ($owner: String!, $name: String!, $number: Int!, $pageSize: Int!, $after: String) {
+					repository(owner: $owner, name: $name) {
+						issue(number: $number) {
+							comments: timelineItems(first: $pageSize, after: $after, itemTypes: [ISSUE_COMMENT]) {
+								...commentFields
+							}
+						}
+					}
+				}
+			` + commentFragment)
+			req.Var("owner", owner)
+			req.Var("name", name)
+			req.Var("number", issue.Number)
+			req.Var("pageSize", pageSize)
+			req.Var("after", issue.Comments.PageInfo.EndCursor)
+			req.Header.Set("Authorization", authHeader)
+
+			var res issueCommentsResponse
+			if err := client.Run(context.Background(), req, &res); err != nil {
+				return errors.Wrap(err, "failed to fetch issue comments")
+			}
+			issue.Comments.Nodes = append(issue.Comments.Nodes, res.Repository.Issue.Comments.Nodes...)
+			issue.Comments.PageInfo = res.Repository.Issue.Comments.PageInfo
+		}
+		return nil
+	})
+}
+
+// returns open issues matching the filter in ascending order of the given field (CREATED_AT or UPDATED_AT), stopping
+// once limit issues have been found if limit is positive
+func getOpenIssues(client *graphql.Client, authHeader string, owner string, name string, orderField string, filter labelFilter, limit int) ([]issue, error) {
+	req := graphql.NewRequest(`
+		query ($owner: String!, $name: String!, $pageSize: Int!, $after: String, $orderField: IssueOrderField!) {
+			repository(owner: $owner, name: $name) {
+				issues(first: $pageSize, after: $after, states: OPEN, orderBy: {field: $orderField, direction: ASC}) {
+					...issueFields
+				}
+			}
+		}
+	` + issueFragment)
 	req.Var("owner", owner)
 	req.Var("name", name)
 	req.Var("pageSize", pageSize)
 	req.Var("after", nil)
+	req.Var("orderField", orderField)
 	req.Header.Set("Authorization", authHeader)
 
 	var issues []issue
@@ -63,21 +287,78 @@ func getIssuesCreatedSince(client *graphql.Client, authHeader string, owner stri
 	for getNextPage {
 		var res issueDatesResponse
 		if err := client.Run(context.Background(), req, &res); err != nil {
-			return nil, errors.Wrap(err, "failed to fetch repo issues")
+			return nil, errors.Wrap(err, "failed to fetch open issues")
 		}
-		newIssues := res.Repository.Issues.Nodes
-		lastIndex := len(newIssues)
-		for lastIndex > 0 && newIssues[lastIndex-1].CreatedAt.Before(since) {
-			lastIndex--
+		issues = append(issues, filterIssues(res.Repository.Issues.Nodes, filter)...)
+		if limit > 0 && len(issues) >= limit {
+			issues = issues[:limit]
+			break
 		}
-		issues = append(issues, newIssues[:lastIndex]...)
-		getNextPage = lastIndex == len(newIssues) && res.Repository.Issues.PageInfo.HasNextPage
+		getNextPage = res.Repository.Issues.PageInfo.HasNextPage
 		req.Var("after", res.Repository.Issues.PageInfo.EndCursor)
 	}
 
+	// only for the issues being returned, however old
+	if err := getMaintainerResponses(client, authHeader, owner, name, issues, time.Time{}); err != nil {
+		return nil, err
+	}
 	return issues, nil
 }
 
+// the number of searches sent in one request, to stay under GitHub's query complexity limit
+const searchBatchSize = 50
+
+// returns the number of open issues matching each of the given search qualifiers, e.g. "label:bug". searches are sent
+// in batches of searchBatchSize per request
+func getOpenIssueCounts(client *graphql.Client, authHeader string, owner string, name string, qualifiers []string) ([]int, error) {
+	var counts []int
+	for start := 0; start < len(qualifiers); start += searchBatchSize {
+		end := start + searchBatchSize
+		if end > len(qualifiers) {
+			end = len(qualifiers)
+		}
+		batchCounts, err := getOpenIssueCountBatch(client, authHeader, owner, name, qualifiers[start:end])
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, batchCounts...)
+	}
+	return counts, nil
+}
+
+// like getOpenIssueCounts, but sends all searches in a single request
+func getOpenIssueCountBatch(client *graphql.Client, authHeader string, owner string, name string, qualifiers []string) ([]int, error) {
+	var searches []string
+	for i := range qualifiers {
+		searches = append(searches, fmt.Sprintf(`
+			search%d: search(query: $query%d, type: ISSUE) {
+				issueCount
+			}
+		`, i, i))
+	}
+	var vars []string
+	for i := range qualifiers {
+		vars = append(vars, fmt.Sprintf("$query%d: String!", i))
+	}
+	req := graphql.NewRequest(fmt.Sprintf("query (%s) {%s}", strings.Join(vars, ", "), strings.Join(searches, "")))
+	for i, qualifier := range qualifiers {
+		req.Var(fmt.Sprintf("query%d", i), fmt.Sprintf("repo:%s/%s is:issue is:open %s", owner, name, qualifier))
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	var res map[string]struct {
+		IssueCount int
+	}
+	if err := client.Run(context.Background(), req, &res); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch open issue counts")
+	}
+	counts := make([]int, len(qualifiers))
+	for i := range qualifiers {
+		counts[i] = res[fmt.Sprintf("search%d", i)].IssueCount
+	}
+	return counts, nil
+}
+
 type defaultBranchResponse struct {
 	Repository struct {
 		DefaultBranchRef struct {
@@ -110,21 +391,69 @@ type pr struct {
 	URL               string
 	State             string
 	CreatedAt         time.Time
+	UpdatedAt         time.Time
 	ClosedAt          time.Time
+	MergedAt          time.Time
 	Merged            bool
+	IsDraft           bool
 	IsCrossRepository bool
-	Author            struct {
+	Repository        struct {
+		NameWithOwner string
+		Owner         struct {
+			Login string
+		}
+	}
+	BaseRefName string
+	HeadRefName string
+	Labels      struct {
+		Nodes []label
+	}
+	Author struct {
+		Login string
+	}
+	AuthorAssociation string
+	MergedBy          struct {
 		Login string
 	}
 	Reviews struct {
 		TotalCount int
 		Nodes      []struct {
 			CreatedAt time.Time
+			State     string
 			Author    struct {
 				Login string
 			}
 		}
 	}
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer struct {
+				Login string // users
+				Slug  string // teams
+			}
+		}
+	}
+	LatestOpinionatedReviews struct {
+		Nodes []struct {
+			State       string
+			SubmittedAt time.Time
+			Author      struct {
+				Login string
+			}
+		}
+	}
+	FirstApproval struct {
+		Nodes []struct {
+			CreatedAt time.Time
+		}
+	}
+	FirstCommit struct {
+		Nodes []struct {
+			Commit struct {
+				AuthoredDate time.Time
+			}
+		}
+	}
 	Commits struct {
 		Nodes []struct {
 			Commit struct {
@@ -138,6 +467,10 @@ type pr struct {
 	}
 }
 
+type label struct {
+	Name string
+}
+
 type checkContext struct {
 	Context   string
 	CreatedAt time.Time
@@ -153,14 +486,54 @@ const prFragment = `
 			state
 			createdAt
 			closedAt
+			mergedAt
 			merged
-			author @include(if: $byRepo) {
+			repository {
+				nameWithOwner
+				owner {
+					login
+				}
+			}
+			baseRefName
+			headRefName
+			labels(first: 20) {
+				nodes {
+					name
+				}
+			}
+			author {
 				login
 			}
+			authorAssociation
+			mergedBy {
+				login
+			}
+			latestOpinionatedReviews(first: 10) {
+				nodes {
+					state
+					submittedAt
+					author {
+						login
+					}
+				}
+			}
+			firstApproval: reviews(first: 1, states: APPROVED) {
+				nodes {
+					createdAt
+				}
+			}
+			firstCommit: commits(first: 1) {
+				nodes {
+					commit {
+						authoredDate
+					}
+				}
+			}
 			reviews(first: 10) {
 				totalCount
-				nodes @include(if: $byRepo) {
+				nodes {
 					createdAt
+					state
 					author {
 						login
 					}
@@ -182,7 +555,17 @@ const prWithCIMetadataFragment = `
 			url
 			createdAt
 			isCrossRepository
-			commits(last: 1) @include(if: $byRepo) {
+			baseRefName
+			headRefName
+			labels(first: 20) {
+				nodes {
+					name
+				}
+			}
+			author {
+				login
+			}
+			commits(last: 1) {
 				nodes {
 					commit {
 						committedDate
@@ -205,8 +588,117 @@ const prWithCIMetadataFragment = `
 	}
 `
 
-func getRepoPRsCreatedSince(client *graphql.Client, authHeader string, owner string, name string, since time.Time, prFragment string) ([]pr, error) {
-	defaultBranchReq := graphql.NewRequest(`
+// matches issues and PRs that have any of Labels (if set) and none of ExcludeLabels
+type labelFilter struct {
+	Labels        []string
+	ExcludeLabels []string
+}
+
+func (f labelFilter) isEmpty() bool {
+	return len(f.Labels) == 0 && len(f.ExcludeLabels) == 0
+}
+
+func (f labelFilter) matches(labels []label) bool {
+	if len(f.Labels) > 0 && !hasAnyLabel(labels, f.Labels) {
+		return false
+	}
+	return !hasAnyLabel(labels, f.ExcludeLabels)
+}
+
+// returns search qualifiers equivalent to the filter, see
+// https://docs.github.com/en/search-github/searching-on-github/searching-issues-and-pull-requests#search-by-label
+func (f labelFilter) searchQualifiers() string {
+	var qualifiers []string
+	if len(f.Labels) > 0 {
+		var quoted []string
+		for _, label := range f.Labels {
+			quoted = append(quoted, strconv.Quote(label))
+		}
+		qualifiers = append(qualifiers, "label:"+strings.Join(quoted, ","))
+	}
+	for _, label := range f.ExcludeLabels {
+		qualifiers = append(qualifiers, "-label:"+strconv.Quote(label))
+	}
+	return strings.Join(qualifiers, " ")
+}
+
+// like getIssuesUpdatedSince, but only returns issues matching the filter and the number of open issues that match it
+func getMatchingIssuesUpdatedSince(client *graphql.Client, authHeader string, owner string, name string, since time.Time, filter labelFilter) ([]issue, int, error) {
+	issues, numOpen, err := getIssuesUpdatedSince(client, authHeader, owner, name, since)
+	if err != nil || filter.isEmpty() {
+		return issues, numOpen, err
+	}
+
+	counts, err := getOpenIssueCounts(client, authHeader, owner, name, []string{filter.searchQualifiers()})
+	if err != nil {
+		return nil, 0, err
+	}
+	return filterIssues(issues, filter), counts[0], nil
+}
+
+func filterIssues(issues []issue, filter labelFilter) []issue {
+	if filter.isEmpty() {
+		return issues
+	}
+	var filtered []issue
+	for _, issue := range issues {
+		if filter.matches(issue.Labels.Nodes) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// restricts the PRs returned by getRepoPRsCreatedSince. the base branch is applied in the query, everything else is
+// matched against each fetched PR
+type prFilter struct {
+	labelFilter
+	BaseBranch string // empty for the repo's default branch, "all" for any branch
+	Authors    []string
+	HeadBranch string // glob pattern, see matchBranch
+}
+
+func (f prFilter) matches(pr pr) bool {
+	if !f.labelFilter.matches(pr.Labels.Nodes) {
+		return false
+	}
+	if len(f.Authors) > 0 && !containsFold(f.Authors, pr.Author.Login) {
+		return false
+	}
+	if f.HeadBranch != "" {
+		if matched, _ := matchBranch(f.HeadBranch, pr.HeadRefName); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// like path.Match, but * also matches /, so that hotfix* matches branches like hotfix/login
+func matchBranch(pattern string, branch string) (bool, error) {
+	return path.Match(strings.Replace(pattern, "/", "\x00", -1), strings.Replace(branch, "/", "\x00", -1))
+}
+
+func hasAnyLabel(labels []label, names []string) bool {
+	for _, label := range labels {
+		if containsFold(names, label.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// label names and logins are case-insensitive on GitHub
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func getDefaultBranch(client *graphql.Client, authHeader string, owner string, name string) (string, error) {
+	req := graphql.NewRequest(`
 		query ($owner: String!, $name: String!) {
 			repository(owner: $owner, name: $name) {
 				defaultBranchRef {
@@ -215,19 +707,68 @@ func getRepoPRsCreatedSince(client *graphql.Client, authHeader string, owner str
 			}
 		}
 	`)
-	defaultBranchReq.Var("owner", owner)
-	defaultBranchReq.Var("name", name)
-	defaultBranchReq.Header.Set("Authorization", authHeader)
+	req.Var("owner", owner)
+	req.Var("name", name)
+	req.Header.Set("Authorization", authHeader)
+
+	var res defaultBranchResponse
+	if err := client.Run(context.Background(), req, &res); err != nil {
+		return "", errors.Wrap(err, "failed to fetch default branch for repo")
+	}
+	return res.Repository.DefaultBranchRef.Name, nil
+}
 
-	var defaultBranchRes defaultBranchResponse
-	if err := client.Run(context.Background(), defaultBranchReq, &defaultBranchRes); err != nil {
-		return nil, errors.Wrap(err, "failed to fetch default branch for repo")
+type openPRCountResponse struct {
+	Repository struct {
+		PullRequests struct {
+			TotalCount int
+		}
 	}
+}
 
+func getOpenPRCount(client *graphql.Client, authHeader string, owner string, name string) (int, error) {
 	req := graphql.NewRequest(`
-		query ($owner: String!, $name: String!, $pageSize: Int!, $after: String, $defaultBranch: String!, $byRepo: Boolean = true) {
+		query ($owner: String!, $name: String!) {
 			repository(owner: $owner, name: $name) {
-				pullRequests(first: $pageSize, after: $after, orderBy: {field: CREATED_AT, direction: DESC}, baseRefName: $defaultBranch) {
+				pullRequests(states: OPEN) {
+					totalCount
+				}
+			}
+		}
+	`)
+	req.Var("owner", owner)
+	req.Var("name", name)
+	req.Header.Set("Authorization", authHeader)
+
+	var res openPRCountResponse
+	if err := client.Run(context.Background(), req, &res); err != nil {
+		return 0, errors.Wrap(err, "failed to fetch open PR count for repo")
+	}
+	return res.Repository.PullRequests.TotalCount, nil
+}
+
+// returns the baseRefName query variable for the filter, nil meaning any branch
+func getBaseBranch(client *graphql.Client, authHeader string, owner string, name string, filter prFilter) (interface{}, error) {
+	switch filter.BaseBranch {
+	case "":
+		return getDefaultBranch(client, authHeader, owner, name)
+	case "all":
+		return nil, nil
+	default:
+		return filter.BaseBranch, nil
+	}
+}
+
+func getRepoPRsCreatedSince(client *graphql.Client, authHeader string, owner string, name string, since time.Time, prFragment string, filter prFilter) ([]pr, error) {
+	baseBranch, err := getBaseBranch(client, authHeader, owner, name, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	req := graphql.NewRequest(`
+		query ($owner: String!, $name: String!, $pageSize: Int!, $after: String, $baseBranch: String) {
+			repository(owner: $owner, name: $name) {
+				pullRequests(first: $pageSize, after: $after, orderBy: {field: CREATED_AT, direction: DESC}, baseRefName: $baseBranch) {
 					...prFields
 				}
 			}
@@ -237,7 +778,7 @@ func getRepoPRsCreatedSince(client *graphql.Client, authHeader string, owner str
 	req.Var("name", name)
 	req.Var("pageSize", pageSize)
 	req.Var("after", nil)
-	req.Var("defaultBranch", defaultBranchRes.Repository.DefaultBranchRef.Name)
+	req.Var("baseBranch", baseBranch)
 	req.Header.Set("Authorization", authHeader)
 
 	var prs []pr
@@ -252,7 +793,11 @@ func getRepoPRsCreatedSince(client *graphql.Client, authHeader string, owner str
 		for lastIndex > 0 && newPrs[lastIndex-1].CreatedAt.Before(since) {
 			lastIndex--
 		}
-		prs = append(prs, newPrs[:lastIndex]...)
+		for _, pr := range newPrs[:lastIndex] {
+			if filter.matches(pr) {
+				prs = append(prs, pr)
+			}
+		}
 		getNextPage = lastIndex == len(newPrs) && res.Repository.PullRequests.PageInfo.HasNextPage
 		req.Var("after", res.Repository.PullRequests.PageInfo.EndCursor)
 	}
@@ -260,9 +805,177 @@ func getRepoPRsCreatedSince(client *graphql.Client, authHeader string, owner str
 	return prs, nil
 }
 
+func getOpenPRsCreatedBefore(client *graphql.Client, authHeader string, owner string, name string, before time.Time, filter prFilter) ([]pr, error) {
+	baseBranch, err := getBaseBranch(client, authHeader, owner, name, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	req := graphql.NewRequest(`
+		query ($owner: String!, $name: String!, $pageSize: Int!, $after: String, $baseBranch: String) {
+			repository(owner: $owner, name: $name) {
+				pullRequests(first: $pageSize, after: $after, states: OPEN, orderBy: {field: CREATED_AT, direction: ASC}, baseRefName: $baseBranch) {
+					nodes {
+						number
+						title
+						url
+						createdAt
+						updatedAt
+						isDraft
+						baseRefName
+						headRefName
+						labels(first: 20) {
+							nodes {
+								name
+							}
+						}
+						author {
+							login
+						}
+						reviewRequests(first: 10) {
+							nodes {
+								requestedReviewer {
+									... on User {
+										login
+									}
+									... on Team {
+										slug
+									}
+								}
+							}
+						}
+						latestOpinionatedReviews(first: 10) {
+							nodes {
+								state
+								submittedAt
+								author {
+									login
+								}
+							}
+						}
+						commits(last: 1) {
+							nodes {
+								commit {
+									committedDate
+									pushedDate
+								}
+							}
+						}
+					}
+					pageInfo {
+						endCursor
+						hasNextPage
+					}
+				}
+			}
+		}
+	`)
+	req.Var("owner", owner)
+	req.Var("name", name)
+	req.Var("pageSize", pageSize)
+	req.Var("after", nil)
+	req.Var("baseBranch", baseBranch)
+	req.Header.Set("Authorization", authHeader)
+
+	var prs []pr
+	getNextPage := true
+	for getNextPage {
+		var res repoPRResponse
+		if err := client.Run(context.Background(), req, &res); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch open repo PRs")
+		}
+		newPrs := res.Repository.PullRequests.Nodes
+		// PRs are in ascending order of creation, so stop at the first one that is too new
+		lastIndex := 0
+		for lastIndex < len(newPrs) && newPrs[lastIndex].CreatedAt.Before(before) {
+			lastIndex++
+		}
+		for _, pr := range newPrs[:lastIndex] {
+			if filter.matches(pr) {
+				prs = append(prs, pr)
+			}
+		}
+		getNextPage = lastIndex == len(newPrs) && res.Repository.PullRequests.PageInfo.HasNextPage
+		req.Var("after", res.Repository.PullRequests.PageInfo.EndCursor)
+	}
+
+	return prs, nil
+}
+
+type orgReposResponse struct {
+	Organization struct {
+		Repositories struct {
+			Nodes    []orgRepo
+			PageInfo pageInfo
+		}
+	}
+}
+
+type orgRepo struct {
+	Name             string
+	IsArchived       bool
+	IsFork           bool
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string
+			}
+		}
+	}
+}
+
+func getOrgRepos(client *graphql.Client, authHeader string, org string, filter repoFilter) ([]orgRepo, error) {
+	req := graphql.NewRequest(`
+		query ($org: String!, $pageSize: Int!, $after: String) {
+			organization(login: $org) {
+				repositories(first: $pageSize, after: $after, orderBy: {field: NAME, direction: ASC}) {
+					nodes {
+						name
+						isArchived
+						isFork
+						repositoryTopics(first: 20) {
+							nodes {
+								topic {
+									name
+								}
+							}
+						}
+					}
+					pageInfo {
+						endCursor
+						hasNextPage
+					}
+				}
+			}
+		}
+	`)
+	req.Var("org", org)
+	req.Var("pageSize", pageSize)
+	req.Var("after", nil)
+	req.Header.Set("Authorization", authHeader)
+
+	var repos []orgRepo
+	getNextPage := true
+	for getNextPage {
+		var res orgReposResponse
+		if err := client.Run(context.Background(), req, &res); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch org repos")
+		}
+		for _, repo := range res.Organization.Repositories.Nodes {
+			if filter.matches(repo) {
+				repos = append(repos, repo)
+			}
+		}
+		getNextPage = res.Organization.Repositories.PageInfo.HasNextPage
+		req.Var("after", res.Organization.Repositories.PageInfo.EndCursor)
+	}
+
+	return repos, nil
+}
+
 func getUserPRsCreatedSince(client *graphql.Client, authHeader string, user string, since time.Time) ([]pr, error) {
 	req := graphql.NewRequest(`
-		query ($user: String!, $pageSize: Int!, $after: String, $byRepo: Boolean = false) {
+		query ($user: String!, $pageSize: Int!, $after: String) {
 			user(login: $user) {
 				pullRequests(first: $pageSize, after: $after, orderBy: {field: CREATED_AT, direction: DESC}) {
 					...prFields
@@ -294,3 +1007,255 @@ func getUserPRsCreatedSince(client *graphql.Client, authHeader string, user stri
 
 	return prs, nil
 }
+
+type userIssuesResponse struct {
+	User struct {
+		Issues struct {
+			Nodes    []issue
+			PageInfo pageInfo
+		}
+	}
+}
+
+func getUserIssuesCreatedSince(client *graphql.Client, authHeader string, user string, since time.Time) ([]issue, error) {
+	req := graphql.NewRequest(`
+		query ($user: String!, $pageSize: Int!, $after: String) {
+			user(login: $user) {
+				issues(first: $pageSize, after: $after, orderBy: {field: CREATED_AT, direction: DESC}) {
+					nodes {
+						number
+						title
+						url
+						state
+						createdAt
+						updatedAt
+						closedAt
+						stateReason
+						repository {
+							nameWithOwner
+							owner {
+								login
+							}
+						}
+					}
+					pageInfo {
+						endCursor
+						hasNextPage
+					}
+				}
+			}
+		}
+	`)
+	req.Var("user", user)
+	req.Var("pageSize", pageSize)
+	req.Var("after", nil)
+	req.Header.Set("Authorization", authHeader)
+
+	var issues []issue
+	getNextPage := true
+	for getNextPage {
+		var res userIssuesResponse
+		if err := client.Run(context.Background(), req, &res); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch user issues")
+		}
+		newIssues := res.User.Issues.Nodes
+		lastIndex := len(newIssues)
+		for lastIndex > 0 && newIssues[lastIndex-1].CreatedAt.Before(since) {
+			lastIndex--
+		}
+		issues = append(issues, newIssues[:lastIndex]...)
+		getNextPage = lastIndex == len(newIssues) && res.User.Issues.PageInfo.HasNextPage
+		req.Var("after", res.User.Issues.PageInfo.EndCursor)
+	}
+
+	return issues, nil
+}
+
+type orgIDResponse struct {
+	Organization struct {
+		ID string
+	}
+}
+
+func getOrgID(client *graphql.Client, authHeader string, org string) (string, error) {
+	req := graphql.NewRequest(`
+		query ($org: String!) {
+			organization(login: $org) {
+				id
+			}
+		}
+	`)
+	req.Var("org", org)
+	req.Header.Set("Authorization", authHeader)
+
+	var res orgIDResponse
+	if err := client.Run(context.Background(), req, &res); err != nil {
+		return "", errors.Wrap(err, "failed to fetch org")
+	}
+	return res.Organization.ID, nil
+}
+
+type teamMembersResponse struct {
+	Organization struct {
+		ID   string
+		Team *struct {
+			Members struct {
+				Nodes []struct {
+					Login string
+				}
+				PageInfo pageInfo
+			}
+		}
+	}
+}
+
+// returns the logins of the team's members, along with the ID of the org
+func getTeamMembers(client *graphql.Client, authHeader string, org string, team string) ([]string, string, error) {
+	req := graphql.NewRequest(`
+		query ($org: String!, $team: String!, $pageSize: Int!, $after: String) {
+			organization(login: $org) {
+				id
+				team(slug: $team) {
+					members(first: $pageSize, after: $after) {
+						nodes {
+							login
+						}
+						pageInfo {
+							endCursor
+							hasNextPage
+						}
+					}
+				}
+			}
+		}
+	`)
+	req.Var("org", org)
+	req.Var("team", team)
+	req.Var("pageSize", pageSize)
+	req.Var("after", nil)
+	req.Header.Set("Authorization", authHeader)
+
+	var members []string
+	var orgID string
+	getNextPage := true
+	for getNextPage {
+		var res teamMembersResponse
+		if err := client.Run(context.Background(), req, &res); err != nil {
+			return nil, "", errors.Wrap(err, "failed to fetch team members")
+		}
+		if res.Organization.Team == nil {
+			// unlike repos and orgs, GitHub returns null rather than an error for unknown teams
+			return nil, "", errors.Errorf("Could not resolve to a Team with the slug '%s'", team)
+		}
+		orgID = res.Organization.ID
+		for _, member := range res.Organization.Team.Members.Nodes {
+			members = append(members, member.Login)
+		}
+		getNextPage = res.Organization.Team.Members.PageInfo.HasNextPage
+		req.Var("after", res.Organization.Team.Members.PageInfo.EndCursor)
+	}
+
+	return members, orgID, nil
+}
+
+type userReviewsResponse struct {
+	User struct {
+		ContributionsCollection struct {
+			PullRequestReviewContributions struct {
+				Nodes    []review
+				PageInfo pageInfo
+			}
+		}
+	}
+}
+
+type review struct {
+	OccurredAt        time.Time
+	PullRequestReview struct {
+		State  string
+		Author struct {
+			Login string
+		}
+	}
+	PullRequest struct {
+		Number     int
+		URL        string
+		Repository struct {
+			NameWithOwner string
+		}
+		Author struct {
+			Login string
+		}
+	}
+}
+
+// returns the reviews the user submitted since the given date. if orgID is not empty, only reviews on that org's repos
+// are returned
+func getUserReviewsSince(client *graphql.Client, authHeader string, user string, orgID string, since time.Time) ([]review, error) {
+	req := graphql.NewRequest(`
+		query ($user: String!, $pageSize: Int!, $after: String, $from: DateTime!, $to: DateTime!, $orgID: ID) {
+			user(login: $user) {
+				contributionsCollection(from: $from, to: $to, organizationID: $orgID) {
+					pullRequestReviewContributions(first: $pageSize, after: $after) {
+						nodes {
+							occurredAt
+							pullRequestReview {
+								state
+								author {
+									login
+								}
+							}
+							pullRequest {
+								number
+								url
+								repository {
+									nameWithOwner
+								}
+								author {
+									login
+								}
+							}
+						}
+						pageInfo {
+							endCursor
+							hasNextPage
+						}
+					}
+				}
+			}
+		}
+	`)
+	req.Var("user", user)
+	req.Var("pageSize", pageSize)
+	if orgID != "" {
+		req.Var("orgID", orgID)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	// GitHub rejects contribution ranges longer than a year, so longer ranges are fetched a year at a time
+	var reviews []review
+	now := time.Now()
+	for from := since; from.Before(now); from = from.AddDate(1, 0, 0) {
+		to := from.AddDate(1, 0, 0)
+		if to.After(now) {
+			to = now
+		}
+		req.Var("from", from)
+		req.Var("to", to)
+		req.Var("after", nil)
+
+		getNextPage := true
+		for getNextPage {
+			var res userReviewsResponse
+			if err := client.Run(context.Background(), req, &res); err != nil {
+				return nil, errors.Wrap(err, "failed to fetch user reviews")
+			}
+			contributions := res.User.ContributionsCollection.PullRequestReviewContributions
+			reviews = append(reviews, contributions.Nodes...)
+			getNextPage = contributions.PageInfo.HasNextPage
+			req.Var("after", contributions.PageInfo.EndCursor)
+		}
+	}
+
+	return reviews, nil
+}
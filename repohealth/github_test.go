@@ -0,0 +1,23 @@
+package repohealth
+
+import "testing"
+
+func TestMatchBranch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		branch  string
+		want    bool
+	}{
+		{"hotfix*", "hotfix/login", true},
+		{"hotfix*", "hotfix-login", true},
+		{"hotfix/*", "hotfix/login", true},
+		{"*/login", "hotfix/login", true},
+		{"hotfix*", "release/hotfix", false},
+		{"hotfix/?", "hotfix/login", false},
+	}
+	for _, test := range tests {
+		if matched, err := matchBranch(test.pattern, test.branch); err != nil || matched != test.want {
+			t.Errorf("matchBranch(%q, %q) = %v, %v, want %v", test.pattern, test.branch, matched, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package repohealth
+
+import (
+	"math"
+)
+
+const (
+	indicatorMedianReviewTime          = "medianReviewTime"          // in sec, over PRs opened in the week
+	indicatorMergeRate                 = "mergeRate"                 // fraction of PRs closed in the week that were merged
+	indicatorMedianIssueResolutionTime = "medianIssueResolutionTime" // in sec, over issues closed in the week
+	indicatorIssueCloseRate            = "issueCloseRate"            // issues closed per issue opened in the week
+	indicatorCIP90                     = "ciP90"                     // in sec, over the slowest check of each PR with CI
+)
+
+// returns the weekly values of an indicator, -1 for weeks without data
+var indicators = map[string]func(issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64{
+	indicatorMedianReviewTime: func(issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64 {
+		var values []float64
+		for _, week := range prs {
+			var reviewTimes []int
+			for _, details := range week.Details {
+				reviewTimes = appendIfSet(reviewTimes, details.ReviewTime)
+			}
+			values = append(values, float64(percentile(reviewTimes, 50)))
+		}
+		return values
+	},
+	indicatorMergeRate: func(issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64 {
+		var values []float64
+		for _, week := range prs {
+			value := -1.0
+			if closed := week.NumMerged + week.NumRejected; closed > 0 {
+				value = float64(week.NumMerged) / float64(closed)
+			}
+			values = append(values, value)
+		}
+		return values
+	},
+	indicatorMedianIssueResolutionTime: func(issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64 {
+		var values []float64
+		for _, week := range issues {
+			values = append(values, float64(week.ResolutionTime.P50))
+		}
+		return values
+	},
+	indicatorIssueCloseRate: func(issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64 {
+		var values []float64
+		for _, week := range issues {
+			value := -1.0
+			if week.NumOpen > 0 {
+				value = float64(week.NumClosed) / float64(week.NumOpen)
+			}
+			values = append(values, value)
+		}
+		return values
+	},
+	indicatorCIP90: func(issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64 {
+		var values []float64
+		for _, week := range ci {
+			values = append(values, float64(percentile(getCIDurations(week.Details), 90)))
+		}
+		return values
+	},
+}
+
+type WeeklyHealth struct {
+	Week       string                 `json:"week"`
+	Score      int                    `json:"score"` // 0-100, -1 if there was no data for any component
+	Grade      string                 `json:"grade"`
+	Components []HealthComponentScore `json:"components"`
+}
+
+type HealthComponentScore struct {
+	Indicator    string  `json:"indicator"`
+	Value        float64 `json:"value"`        // -1 if there was no data, in which case the component is left out
+	Score        float64 `json:"score"`        // 0-100
+	Weight       float64 `json:"weight"`       // normalized over the components with data
+	Contribution float64 `json:"contribution"` // weight * score, these add up to the overall score
+}
+
+// the weekly metrics must all cover the same weeks
+func GetHealthScore(config HealthConfig, issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []WeeklyHealth {
+	componentValues := make([][]float64, len(config.Components))
+	for i, component := range config.Components {
+		componentValues[i] = indicators[component.Indicator](issues, prs, ci)
+	}
+
+	health := []WeeklyHealth{}
+	for week := range prs {
+		weekHealth := WeeklyHealth{Week: prs[week].Week, Components: []HealthComponentScore{}}
+		totalWeight := 0.0
+		for i, component := range config.Components {
+			value := componentValues[i][week]
+			componentScore := HealthComponentScore{Indicator: component.Indicator, Value: value}
+			if value >= 0 {
+				componentScore.Score = scoreIndicator(value, component.Good, component.Bad)
+				componentScore.Weight = component.Weight
+				totalWeight += component.Weight
+			}
+			weekHealth.Components = append(weekHealth.Components, componentScore)
+		}
+
+		if totalWeight == 0 {
+			weekHealth.Score = -1
+			health = append(health, weekHealth)
+			continue
+		}
+		score := 0.0
+		for i := range weekHealth.Components {
+			component := &weekHealth.Components[i]
+			component.Weight /= totalWeight
+			component.Contribution = component.Weight * component.Score
+			score += component.Contribution
+		}
+		weekHealth.Score = int(math.Round(score))
+		weekHealth.Grade = getGrade(weekHealth.Score)
+		health = append(health, weekHealth)
+	}
+	return health
+}
+
+// returns the weekly values of an indicator or of the health score, -1 for weeks without data
+func getMetricSeries(config HealthConfig, metric string, issues []WeeklyIssueMetrics, prs []WeeklyPRMetrics, ci []WeeklyCIMetrics) []float64 {
+	if metric != alertMetricHealthScore {
+		return indicators[metric](issues, prs, ci)
+	}
+	var values []float64
+	for _, week := range GetHealthScore(config, issues, prs, ci) {
+		values = append(values, float64(week.Score))
+	}
+	return values
+}
+
+func scoreIndicator(value float64, good float64, bad float64) float64 {
+	score := (value - bad) / (good - bad) * 100
+	return math.Max(0, math.Min(100, score))
+}
+
+func getGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
@@ -0,0 +1,17 @@
+package repohealth
+
+import "testing"
+
+func TestCIP90IgnoresPRsWithoutChecks(t *testing.T) {
+	ci := []WeeklyCIMetrics{
+		{Week: "2026-10-04", Details: []CIDetails{{PR: 1}, {PR: 2}}},
+		{Week: "2026-10-11", Details: []CIDetails{{PR: 3}, {PR: 4, MaxCheckDuration: 600, NumChecks: 2}}},
+	}
+	values := indicators[indicatorCIP90](nil, nil, ci)
+	if values[0] != -1 {
+		t.Errorf("got %v for a week without CI, want -1", values[0])
+	}
+	if values[1] != 600 {
+		t.Errorf("got %v, want the duration of the only PR with checks", values[1])
+	}
+}
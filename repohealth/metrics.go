@@ -0,0 +1,196 @@
+package repohealth
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	reviewTimeBuckets = []float64{15 * 60, 60 * 60, 4 * 60 * 60, 24 * 60 * 60, 3 * 24 * 60 * 60, 7 * 24 * 60 * 60}
+	ciDurationBuckets = []float64{60, 5 * 60, 10 * 60, 20 * 60, 30 * 60, 60 * 60, 2 * 60 * 60}
+
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// serves the Syncer's latest snapshots in the Prometheus text exposition format. the output is rendered after each
+// sync rather than per scrape
+type Exporter struct {
+	mu      sync.RWMutex
+	metrics []byte
+
+	// histograms are cumulative since the exporter started, observing each PR once, on the first sync that sees it
+	// closed, so that counts never go down as PRs leave the synced window
+	reviewTimes map[string]*histogram            // by repo
+	ciDurations map[string]map[string]*histogram // by repo, then check
+	observed    map[string]map[int]bool          // numbers of the closed PRs already observed, by repo
+}
+
+func NewExporter() *Exporter {
+	return &Exporter{
+		reviewTimes: map[string]*histogram{},
+		ciDurations: map[string]map[string]*histogram{},
+		observed:    map[string]map[int]bool{},
+	}
+}
+
+// observes newly closed PRs and renders the given snapshots; register with Syncer.OnSync
+func (e *Exporter) Update(snapshots map[string]repoSnapshot) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var repos []string
+	for repo := range snapshots {
+		repos = append(repos, repo)
+		e.observe(repo, snapshots[repo])
+	}
+	sort.Strings(repos)
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "repo_health_open_prs", "gauge", "Number of open PRs.")
+	for _, repo := range repos {
+		writeSample(&buf, "repo_health_open_prs", labels("repo", repo), float64(snapshots[repo].NumOpenPRs))
+	}
+
+	writeHeader(&buf, "repo_health_issue_backlog", "gauge", "Number of open issues.")
+	for _, repo := range repos {
+		writeSample(&buf, "repo_health_issue_backlog", labels("repo", repo), float64(snapshots[repo].Data.NumOpen))
+	}
+
+	writeHeader(&buf, "repo_health_review_time_seconds", "histogram", "Time from PR creation to first review, observed when a PR is closed.")
+	for _, repo := range repos {
+		if h := e.reviewTimes[repo]; h != nil {
+			h.write(&buf, "repo_health_review_time_seconds", labels("repo", repo))
+		}
+	}
+
+	writeHeader(&buf, "repo_health_ci_duration_seconds", "histogram", "Time from push to completion of each check on the latest commit of a PR, observed when the PR is closed.")
+	for _, repo := range repos {
+		checks := e.ciDurations[repo]
+		var names []string
+		for name := range checks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			checks[name].write(&buf, "repo_health_ci_duration_seconds", labels("repo", repo, "check", name))
+		}
+	}
+
+	writeHeader(&buf, "repo_health_last_sync_timestamp_seconds", "gauge", "Unix time of the last successful sync.")
+	for _, repo := range repos {
+		writeSample(&buf, "repo_health_last_sync_timestamp_seconds", labels("repo", repo), float64(snapshots[repo].SyncedAt.Unix()))
+	}
+
+	e.metrics = buf.Bytes()
+}
+
+// observes the PRs closed since the previous sync of the repo, and forgets the ones that have left the synced window
+func (e *Exporter) observe(repo string, snapshot repoSnapshot) {
+	observed := e.observed[repo]
+	closed := map[int]bool{}
+	for _, pr := range snapshot.Data.PRs {
+		if pr.State == "OPEN" {
+			continue
+		}
+		closed[pr.Number] = true
+		if observed[pr.Number] {
+			continue
+		}
+		if reviewTime := getReviewTime(pr); reviewTime >= 0 {
+			if e.reviewTimes[repo] == nil {
+				e.reviewTimes[repo] = newHistogram(reviewTimeBuckets)
+			}
+			e.reviewTimes[repo].observe(float64(reviewTime))
+		}
+	}
+
+	for _, pr := range snapshot.Data.CIPRs {
+		if !closed[pr.Number] || observed[pr.Number] {
+			continue
+		}
+		if e.ciDurations[repo] == nil {
+			e.ciDurations[repo] = map[string]*histogram{}
+		}
+		checks := e.ciDurations[repo]
+		start := getStatusStartDate(pr)
+		for _, context := range pr.Commits.Nodes[0].Commit.Status.Contexts {
+			if checks[context.Context] == nil {
+				checks[context.Context] = newHistogram(ciDurationBuckets)
+			}
+			checks[context.Context].observe(context.CreatedAt.Sub(start).Seconds())
+		}
+	}
+	e.observed[repo] = closed
+}
+
+func (e *Exporter) ServeMetrics(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(e.metrics)
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []int // cumulative
+	sum     float64
+	count   int
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]int, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *histogram) write(buf *bytes.Buffer, name string, labelPairs string) {
+	for i, bound := range h.buckets {
+		writeSample(buf, name+"_bucket", withLabel(labelPairs, "le", formatFloat(bound)), float64(h.counts[i]))
+	}
+	writeSample(buf, name+"_bucket", withLabel(labelPairs, "le", "+Inf"), float64(h.count))
+	writeSample(buf, name+"_sum", labelPairs, h.sum)
+	writeSample(buf, name+"_count", labelPairs, float64(h.count))
+}
+
+func writeHeader(buf *bytes.Buffer, name string, metricType string, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeSample(buf *bytes.Buffer, name string, labelPairs string, value float64) {
+	fmt.Fprintf(buf, "%s{%s} %s\n", name, labelPairs, formatFloat(value))
+}
+
+// returns the given alternating names and values formatted as label pairs, e.g. repo="gracew/repo-health"
+func labels(namesAndValues ...string) string {
+	var pairs []string
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, namesAndValues[i], labelValueEscaper.Replace(namesAndValues[i+1])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func withLabel(labelPairs string, name string, value string) string {
+	if labelPairs == "" {
+		return labels(name, value)
+	}
+	return labelPairs + "," + labels(name, value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,45 @@
+package repohealth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getTestMetrics(e *Exporter) string {
+	recorder := httptest.NewRecorder()
+	e.ServeMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil), nil)
+	return recorder.Body.String()
+}
+
+func TestExporterObservesEachClosedPROnce(t *testing.T) {
+	var open, closed pr
+	unmarshal(t, `{"number": 1, "state": "OPEN", "createdAt": "2026-10-05T00:00:00Z", "author": {"login": "author"},
+		"reviews": {"nodes": [{"createdAt": "2026-10-05T00:10:00Z", "author": {"login": "reviewer"}}]}}`, &open)
+	unmarshal(t, `{"number": 2, "state": "MERGED", "createdAt": "2026-10-05T00:00:00Z", "author": {"login": "author"},
+		"reviews": {"nodes": [{"createdAt": "2026-10-05T02:00:00Z", "author": {"login": "reviewer"}}]}}`, &closed)
+	snapshot := func(prs ...pr) map[string]repoSnapshot {
+		return map[string]repoSnapshot{"o/r": {Data: repoData{Repo: "o/r", PRs: prs}}}
+	}
+
+	e := NewExporter()
+	e.Update(snapshot(open, closed))
+	e.Update(snapshot(open, closed))
+	metrics := getTestMetrics(e)
+	for _, want := range []string{
+		`repo_health_review_time_seconds_bucket{repo="o/r",le="3600"} 0`,
+		`repo_health_review_time_seconds_bucket{repo="o/r",le="14400"} 1`,
+		`repo_health_review_time_seconds_count{repo="o/r"} 1`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("got metrics\n%s\nwant them to contain %s", metrics, want)
+		}
+	}
+
+	// counts are kept once the PR leaves the synced window, and PRs are observed when they close
+	open.State = "CLOSED"
+	e.Update(snapshot(open))
+	if want := `repo_health_review_time_seconds_count{repo="o/r"} 2`; !strings.Contains(getTestMetrics(e), want) {
+		t.Errorf("got metrics\n%s\nwant them to contain %s", getTestMetrics(e), want)
+	}
+}
@@ -0,0 +1,178 @@
+package repohealth
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// max number of repos fetched at once, to stay clear of GitHub's secondary rate limits
+const maxParallelism = 4
+
+// restricts the repos returned by getOrgRepos. archived repos and forks are skipped unless requested
+type repoFilter struct {
+	Include  []string // glob patterns, see path.Match
+	Exclude  []string
+	Archived bool
+	Forks    bool
+	Topics   []string // repo must have at least one of these topics
+}
+
+func (f repoFilter) matches(repo orgRepo) bool {
+	if repo.IsArchived && !f.Archived || repo.IsFork && !f.Forks {
+		return false
+	}
+	if len(f.Include) > 0 && !matchesAnyPattern(f.Include, repo.Name) {
+		return false
+	}
+	if matchesAnyPattern(f.Exclude, repo.Name) {
+		return false
+	}
+	if len(f.Topics) > 0 {
+		for _, topic := range repo.RepositoryTopics.Nodes {
+			if containsFold(f.Topics, topic.Topic.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// calls fn for 0..n-1 with at most parallelism calls running at once, and returns the first error
+func forEachParallel(n int, parallelism int, fn func(i int) error) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, parallelism)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+type OrgIssueMetrics struct {
+	Merged []WeeklyIssueMetrics `json:"merged"`
+	Repos  []RepoIssueMetrics   `json:"repos"`
+}
+
+type RepoIssueMetrics struct {
+	Repo    string               `json:"repo"`
+	Metrics []WeeklyIssueMetrics `json:"metrics"`
+}
+
+type OrgPRMetrics struct {
+	Merged []WeeklyPRMetrics `json:"merged"`
+	Repos  []RepoPRMetrics   `json:"repos"`
+}
+
+type RepoPRMetrics struct {
+	Repo    string            `json:"repo"`
+	Metrics []WeeklyPRMetrics `json:"metrics"`
+}
+
+type OrgCIMetrics struct {
+	Merged []WeeklyCIMetrics `json:"merged"`
+	Repos  []RepoCIMetrics   `json:"repos"`
+}
+
+type RepoCIMetrics struct {
+	Repo    string            `json:"repo"`
+	Metrics []WeeklyCIMetrics `json:"metrics"`
+}
+
+// repoIssues[i] and repoNumOpen[i] are the issues and open issue count of repos[i]
+func GetOrgIssueScore(repos []string, repoIssues [][]issue, repoNumOpen []int, since time.Time, numWeeks int, excludeNotPlanned bool) OrgIssueMetrics {
+	var allIssues []issue
+	numOpen := 0
+	metrics := OrgIssueMetrics{Repos: []RepoIssueMetrics{}}
+	for i, repo := range repos {
+		allIssues = append(allIssues, repoIssues[i]...)
+		numOpen += repoNumOpen[i]
+		metrics.Repos = append(metrics.Repos, RepoIssueMetrics{
+			Repo:    repo,
+			Metrics: GetIssueScore(repoIssues[i], repoNumOpen[i], since, numWeeks, excludeNotPlanned),
+		})
+	}
+	metrics.Merged = GetIssueScore(allIssues, numOpen, since, numWeeks, excludeNotPlanned)
+	return metrics
+}
+
+// repoPRs[i] are the PRs of repos[i]
+func GetOrgPRScore(repos []string, repoPRs [][]pr, since time.Time, numWeeks int) OrgPRMetrics {
+	var allPRs []pr
+	metrics := OrgPRMetrics{Repos: []RepoPRMetrics{}}
+	for i, repo := range repos {
+		allPRs = append(allPRs, repoPRs[i]...)
+		metrics.Repos = append(metrics.Repos, RepoPRMetrics{
+			Repo:    repo,
+			Metrics: GetPRScore(repoPRs[i], since, numWeeks),
+		})
+	}
+	metrics.Merged = GetPRScore(allPRs, since, numWeeks)
+	return metrics
+}
+
+// repoPRs[i] are the PRs of repos[i], fetched with prWithCIMetadataFragment
+func GetOrgCIScore(repos []string, repoPRs [][]pr, since time.Time, numWeeks int) OrgCIMetrics {
+	var allPRs []pr
+	metrics := OrgCIMetrics{Repos: []RepoCIMetrics{}}
+	for i, repo := range repos {
+		allPRs = append(allPRs, repoPRs[i]...)
+		metrics.Repos = append(metrics.Repos, RepoCIMetrics{
+			Repo:    repo,
+			Metrics: GetCIScore(repoPRs[i], since, numWeeks),
+		})
+	}
+	metrics.Merged = GetCIScore(allPRs, since, numWeeks)
+	return metrics
+}
+
+type TeamPRMetrics struct {
+	Members []string              `json:"members"`
+	PRs     []WeeklyPRMetrics     `json:"prs"`     // authored by members in the org's repos
+	Reviews []WeeklyReviewMetrics `json:"reviews"` // given by members in the org's repos
+}
+
+// memberPRs[i] and memberReviews[i] are the PRs and reviews of members[i]. only PRs in the org's repos are scored
+func GetTeamPRScore(org string, members []string, memberPRs [][]pr, memberReviews [][]review, since time.Time, numWeeks int) TeamPRMetrics {
+	var orgPRs []pr
+	var reviews []review
+	for i := range members {
+		for _, pr := range memberPRs[i] {
+			if strings.EqualFold(pr.Repository.Owner.Login, org) {
+				orgPRs = append(orgPRs, pr)
+			}
+		}
+		reviews = append(reviews, memberReviews[i]...)
+	}
+
+	return TeamPRMetrics{
+		Members: members,
+		PRs:     GetPRScore(orgPRs, since, numWeeks),
+		Reviews: GetReviewScore(reviews, since, numWeeks),
+	}
+}
@@ -1,40 +1,175 @@
 package repohealth
 
 import (
+	"math"
+	"sort"
+	"strings"
 	"time"
 )
 
 type WeeklyIssueMetrics struct {
-	Week      string         `json:"week"`
-	NumClosed int            `json:"closed"`
-	NumOpen   int            `json:"opened"`
-	Details   []IssueDetails `json:"details"`
+	Week               string         `json:"week"`
+	NumClosed          int            `json:"closed"`
+	NumOpen            int            `json:"opened"`
+	NumBacklog         int            `json:"backlog"` // open issues at the end of the week, -1 if unknown
+	NumReopened        int            `json:"reopened"`
+	ClosedByReason     map[string]int `json:"closedByReason"`     // see issue.StateReason
+	ResolutionTime     Percentiles    `json:"resolutionTime"`     // over issues closed this week
+	NumClosedByPR      int            `json:"closedByPR"`         // closed this week and fixed by a merged PR
+	ClosedByPRFraction float64        `json:"closedByPRFraction"` // 0 if no issues were closed this week
+	FixLeadTime        Percentiles    `json:"fixLeadTime"`        // from issue opened to fix merged
+	Triage             IssueTriage    `json:"triage"`             // over issues opened this week
+	Details            []IssueDetails `json:"details"`
+}
+
+type LabelIssueMetrics struct {
+	Label   string               `json:"label"` // empty for issues without labels
+	Metrics []WeeklyIssueMetrics `json:"metrics"`
+}
+
+type AssociationIssueMetrics struct {
+	Association string               `json:"association"` // see getAuthorGroup
+	Metrics     []WeeklyIssueMetrics `json:"metrics"`
+}
+
+type LinkedPR struct {
+	Number   int       `json:"number"`
+	URL      string    `json:"url"`
+	MergedAt time.Time `json:"mergedAt"`
+}
+
+type IssueTriage struct {
+	FirstResponse      Percentiles `json:"firstResponse"`
+	FirstLabel         Percentiles `json:"firstLabel"`
+	FirstAssignee      Percentiles `json:"firstAssignee"`
+	NumWithoutResponse int         `json:"withoutResponse"`
+}
+
+// in sec, will be -1 if there are no values
+type Percentiles struct {
+	P50 int `json:"p50"`
+	P90 int `json:"p90"`
 }
 
 type IssueDetails struct {
-	Number         int    `json:"number"`
-	Title          string `json:"title"`
-	URL            string `json:"url"`
-	ResolutionTime int    `json:"resolutionTime"` // in sec, will be -1 if issue has not yet been resolved
-	State          string `json:"state"`
+	Number         int        `json:"number"`
+	Title          string     `json:"title"`
+	URL            string     `json:"url"`
+	ResolutionTime int        `json:"resolutionTime"` // in sec, will be -1 if issue has not yet been resolved
+	State          string     `json:"state"`
+	StateReason    string     `json:"stateReason"`
+	Age            int        `json:"age"` // in sec since the issue was opened
+	LastActivity   time.Time  `json:"lastActivity"`
+	Assignees      []string   `json:"assignees"`
+	Labels         []string   `json:"labels"`
+	Association    string     `json:"association"`
+	FixedBy        []LinkedPR `json:"fixedBy"`
+	// in sec since the issue was opened, will be -1 if it has not happened yet
+	FirstResponseTime int `json:"firstResponseTime"`
+	FirstLabelTime    int `json:"firstLabelTime"`
+	FirstAssigneeTime int `json:"firstAssigneeTime"`
+}
+
+// orderings supported by SortIssueDetails
+var issueDetailsLess = map[string]func(a, b IssueDetails) bool{
+	"age": func(a, b IssueDetails) bool {
+		return a.Age > b.Age
+	},
+	"lastActivity": func(a, b IssueDetails) bool {
+		return a.LastActivity.Before(b.LastActivity)
+	},
+	"resolutionTime": func(a, b IssueDetails) bool {
+		return a.ResolutionTime > b.ResolutionTime
+	},
 }
 
 type WeeklyPRMetrics struct {
-	Week        string      `json:"week"`
-	NumMerged   int         `json:"merged"`
-	NumRejected int         `json:"rejected"`
-	NumOpen     int         `json:"opened"`
-	Details     []PRDetails `json:"details"`
+	Week        string       `json:"week"`
+	NumMerged   int          `json:"merged"`
+	NumRejected int          `json:"rejected"`
+	NumOpen     int          `json:"opened"`
+	CycleTime   PRCycleTime  `json:"cycleTime"` // median of each phase over PRs opened this week
+	Compliance  PRCompliance `json:"compliance"`
+	Details     []PRDetails  `json:"details"`
+}
+
+// counts of PRs merged this week that bypassed review policy
+type PRCompliance struct {
+	NumMergedWithoutApproval      int `json:"mergedWithoutApproval"`
+	NumSelfMerged                 int `json:"selfMerged"`
+	NumMergedWithChangesRequested int `json:"mergedWithChangesRequested"`
 }
 
 type PRDetails struct {
-	Number         int    `json:"number"`
-	Title          string `json:"title"`
-	URL            string `json:"url"`
-	ResolutionTime int    `json:"resolutionTime"` // in sec, will be -1 if PR has not yet been resolved
-	ReviewTime     int    `json:"reviewTime"`     // in sec, will be -1 if PR has not yet been reviewed
-	NumReviews     int    `json:"reviews"`
-	State          string `json:"state"`
+	Number         int         `json:"number"`
+	Title          string      `json:"title"`
+	URL            string      `json:"url"`
+	ResolutionTime int         `json:"resolutionTime"` // in sec, will be -1 if PR has not yet been resolved
+	ReviewTime     int         `json:"reviewTime"`     // in sec, will be -1 if PR has not yet been reviewed
+	NumReviews     int         `json:"reviews"`
+	State          string      `json:"state"`
+	CycleTime      PRCycleTime `json:"cycleTime"`
+	NumApprovals   int         `json:"approvals"`
+	MergedBy       string      `json:"mergedBy"`
+	Association    string      `json:"association"`
+}
+
+// all phases are in sec, and will be -1 if the PR has not yet reached the end of the phase
+type PRCycleTime struct {
+	Coding int `json:"coding"` // first commit to PR creation
+	Pickup int `json:"pickup"` // PR creation to first review
+	Review int `json:"review"` // first review to first approval
+	Merge  int `json:"merge"`  // first approval to merge
+}
+
+type AssociationPRMetrics struct {
+	Association string            `json:"association"` // see getAuthorGroup
+	Metrics     []WeeklyPRMetrics `json:"metrics"`
+}
+
+type AuthorPRMetrics struct {
+	Author  string            `json:"author"`
+	Metrics []WeeklyPRMetrics `json:"metrics"`
+}
+
+type StalePRDetails struct {
+	Number             int      `json:"number"`
+	Title              string   `json:"title"`
+	URL                string   `json:"url"`
+	Author             string   `json:"author"`
+	Draft              bool     `json:"draft"`
+	Age                int      `json:"age"`  // in sec
+	Idle               int      `json:"idle"` // in sec since last activity
+	Status             string   `json:"status"`
+	RequestedReviewers []string `json:"requestedReviewers"`
+}
+
+const (
+	statusAwaitingReview = "awaitingReview"
+	statusAwaitingAuthor = "awaitingAuthor"
+	statusApproved       = "approved"
+)
+
+// lower sorts first in the stale PR report
+var statusUrgency = map[string]int{
+	statusAwaitingReview: 0,
+	statusAwaitingAuthor: 1,
+	statusApproved:       2,
+}
+
+type WeeklyReviewMetrics struct {
+	Week       string          `json:"week"`
+	NumReviews int             `json:"reviews"`
+	Details    []ReviewDetails `json:"details"`
+}
+
+type ReviewDetails struct {
+	PR       int    `json:"pr"`
+	PRURL    string `json:"prUrl"`
+	Repo     string `json:"repo"`
+	Author   string `json:"author"` // of the PR
+	Reviewer string `json:"reviewer"`
+	State    string `json:"state"`
 }
 
 type WeeklyCIMetrics struct {
@@ -48,75 +183,380 @@ type CIDetails struct {
 	MaxCheckName     string `json:"maxCheckName"`
 	MaxCheckDuration int    `json:"maxCheckDuration"` // in sec
 	MaxCheckURL      string `json:"maxCheckUrl"`
+	NumChecks        int    `json:"checks"` // on the PR's latest commit, 0 if no CI ran
 }
 
 const pageSize = 100 // default is 30
 const dateFormat = "2006-01-02"
 
-func GetIssueScore(issues []issue, since time.Time, numWeeks int) []WeeklyIssueMetrics {
+// issues should include every issue updated since the start date, so that closures of older issues are counted.
+// numOpen is the number of issues open now, which is used to work backwards to the backlog at the end of each week. it
+// can be -1 if unknown. issues closed as not planned are left out of resolution times if excludeNotPlanned is set
+func GetIssueScore(issues []issue, numOpen int, since time.Time, numWeeks int, excludeNotPlanned bool) []WeeklyIssueMetrics {
 	weekToNumIssuesOpened := map[int]int{}
 	weekToNumIssuesClosed := map[int]int{}
+	weekToNumIssuesReopened := map[int]int{}
+	weekToClosedByReason := map[int]map[string]int{}
+	weekToNumIssuesClosedByPR := map[int]int{}
+	weekToFixLeadTimes := map[int][]int{}
 	weekToIssueDetails := map[int][]IssueDetails{}
+	weekToResolutionTimes := map[int][]int{}
 
+	now := time.Now()
 	secondsInWeek := 60 * 60 * 24 * 7
 	for _, issue := range issues {
 		createdWeek := int(issue.CreatedAt.Sub(since).Seconds()) / secondsInWeek
-		weekToNumIssuesOpened[createdWeek]++
+		if !issue.CreatedAt.Before(since) {
+			weekToNumIssuesOpened[createdWeek]++
+		}
+
+		resolutionTime := -1
+		if issue.State == "CLOSED" {
+			resolutionTime = int(issue.ClosedAt.Sub(issue.CreatedAt).Seconds())
+		}
+
+		fixedBy := getFixedBy(issue)
 
 		if issue.ClosedAt.After(since) {
 			closedWeek := int(issue.ClosedAt.Sub(since).Seconds()) / secondsInWeek
 			weekToNumIssuesClosed[closedWeek]++
-			weekToIssueDetails[createdWeek] = append(weekToIssueDetails[createdWeek], IssueDetails{
-				Number:         issue.Number,
-				Title:          issue.Title,
-				URL:            issue.URL,
-				ResolutionTime: int(issue.ClosedAt.Sub(issue.CreatedAt).Seconds()),
-			})
+			if weekToClosedByReason[closedWeek] == nil {
+				weekToClosedByReason[closedWeek] = map[string]int{}
+			}
+			weekToClosedByReason[closedWeek][getStateReason(issue)]++
+			if !excludeNotPlanned || issue.StateReason != "NOT_PLANNED" {
+				weekToResolutionTimes[closedWeek] = appendIfSet(weekToResolutionTimes[closedWeek], resolutionTime)
+			}
+			if issue.State == "CLOSED" && len(fixedBy) > 0 {
+				weekToNumIssuesClosedByPR[closedWeek]++
+				weekToFixLeadTimes[closedWeek] = append(weekToFixLeadTimes[closedWeek], int(fixedBy[0].MergedAt.Sub(issue.CreatedAt).Seconds()))
+			}
+		}
+		for _, event := range issue.ReopenedEvents.Nodes {
+			if event.CreatedAt.After(since) {
+				weekToNumIssuesReopened[int(event.CreatedAt.Sub(since).Seconds())/secondsInWeek]++
+			}
 		}
 
+		if !issue.CreatedAt.Before(since) {
+			weekToIssueDetails[createdWeek] = append(weekToIssueDetails[createdWeek], getIssueDetails(issue, now))
+		}
 	}
 
 	metrics := []WeeklyIssueMetrics{}
 	for week := 0; week < numWeeks; week++ {
+		closedByPRFraction := 0.0
+		if weekToNumIssuesClosed[week] > 0 {
+			closedByPRFraction = float64(weekToNumIssuesClosedByPR[week]) / float64(weekToNumIssuesClosed[week])
+		}
 		metrics = append(metrics, WeeklyIssueMetrics{
-			Week:      since.AddDate(0, 0, week*7).Format(dateFormat),
-			NumOpen:   weekToNumIssuesOpened[week],
-			NumClosed: weekToNumIssuesClosed[week],
-			Details:   weekToIssueDetails[week],
+			Week:               since.AddDate(0, 0, week*7).Format(dateFormat),
+			NumOpen:            weekToNumIssuesOpened[week],
+			NumClosed:          weekToNumIssuesClosed[week],
+			NumBacklog:         getBacklogAt(issues, numOpen, since.AddDate(0, 0, (week+1)*7)),
+			NumReopened:        weekToNumIssuesReopened[week],
+			ClosedByReason:     weekToClosedByReason[week],
+			ResolutionTime:     getPercentiles(weekToResolutionTimes[week]),
+			NumClosedByPR:      weekToNumIssuesClosedByPR[week],
+			ClosedByPRFraction: closedByPRFraction,
+			FixLeadTime:        getPercentiles(weekToFixLeadTimes[week]),
+			Triage:             getIssueTriage(weekToIssueDetails[week]),
+			Details:            weekToIssueDetails[week],
 		})
 	}
 	return metrics
 }
 
+// returns the sorted label groups for the issues. if the filter includes labels only those are returned, otherwise
+// every label on the issues is returned, plus the empty label if any issue is unlabeled
+func GetIssueLabels(issues []issue, filter labelFilter) []string {
+	if len(filter.Labels) > 0 {
+		labels := append([]string(nil), filter.Labels...)
+		sort.Strings(labels)
+		return labels
+	}
+
+	seen := map[string]bool{}
+	for _, issue := range issues {
+		if len(issue.Labels.Nodes) == 0 {
+			seen[""] = true
+		}
+		for _, label := range issue.Labels.Nodes {
+			seen[label.Name] = true
+		}
+	}
+	var labels []string
+	for label := range seen {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// scores the issues with each label separately; an issue with several labels counts towards each of them.
+// labelToNumOpen has the number of currently open issues for each label group, see GetIssueLabels
+func GetIssueScoreByLabel(issues []issue, labelToNumOpen map[string]int, since time.Time, numWeeks int, excludeNotPlanned bool) []LabelIssueMetrics {
+	var labels []string
+	for label := range labelToNumOpen {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	labelMetrics := []LabelIssueMetrics{}
+	for _, label := range labels {
+		var labelIssues []issue
+		for _, issue := range issues {
+			if label == "" && len(issue.Labels.Nodes) == 0 || label != "" && hasAnyLabel(issue.Labels.Nodes, []string{label}) {
+				labelIssues = append(labelIssues, issue)
+			}
+		}
+		labelMetrics = append(labelMetrics, LabelIssueMetrics{
+			Label:   label,
+			Metrics: GetIssueScore(labelIssues, labelToNumOpen[label], since, numWeeks, excludeNotPlanned),
+		})
+	}
+	return labelMetrics
+}
+
+func getIssueTriage(details []IssueDetails) IssueTriage {
+	var firstResponse, firstLabel, firstAssignee []int
+	numWithoutResponse := 0
+	for _, d := range details {
+		firstResponse = appendIfSet(firstResponse, d.FirstResponseTime)
+		firstLabel = appendIfSet(firstLabel, d.FirstLabelTime)
+		firstAssignee = appendIfSet(firstAssignee, d.FirstAssigneeTime)
+		if d.FirstResponseTime < 0 {
+			numWithoutResponse++
+		}
+	}
+	return IssueTriage{
+		FirstResponse:      getPercentiles(firstResponse),
+		FirstLabel:         getPercentiles(firstLabel),
+		FirstAssignee:      getPercentiles(firstAssignee),
+		NumWithoutResponse: numWithoutResponse,
+	}
+}
+
+const (
+	authorGroupMaintainer = "maintainer"
+	authorGroupCommunity  = "community"
+)
+
+// splits authors into maintainers (owners, members and collaborators) and the community (contributors, first-time
+// contributors and everyone else)
+func getAuthorGroup(authorAssociation string) string {
+	if isMaintainer(authorAssociation) {
+		return authorGroupMaintainer
+	}
+	return authorGroupCommunity
+}
+
+// scores issues opened by maintainers and by the community separately. the backlog of each group is unknown since
+// GitHub cannot search by author association
+func GetIssueScoreByAssociation(issues []issue, since time.Time, numWeeks int, excludeNotPlanned bool) []AssociationIssueMetrics {
+	groupToIssues := map[string][]issue{}
+	for _, issue := range issues {
+		group := getAuthorGroup(issue.AuthorAssociation)
+		groupToIssues[group] = append(groupToIssues[group], issue)
+	}
+
+	associationMetrics := []AssociationIssueMetrics{}
+	for _, group := range []string{authorGroupMaintainer, authorGroupCommunity} {
+		associationMetrics = append(associationMetrics, AssociationIssueMetrics{
+			Association: group,
+			Metrics:     GetIssueScore(groupToIssues[group], -1, since, numWeeks, excludeNotPlanned),
+		})
+	}
+	return associationMetrics
+}
+
+// whether the comment is a maintainer responding to someone else's issue
+func isMaintainerResponse(issue issue, comment timelineItem) bool {
+	return comment.Author.Login != issue.Author.Login && isMaintainer(comment.AuthorAssociation)
+}
+
+// returns the first comment by a maintainer responding to someone else's issue, or nil if there is none among the
+// fetched comments
+func getFirstMaintainerResponse(issue issue) *timelineItem {
+	for i, comment := range issue.Comments.Nodes {
+		if isMaintainerResponse(issue, comment) {
+			return &issue.Comments.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// whether the author association is one of a repo maintainer, see
+// https://docs.github.com/en/graphql/reference/enums#commentauthorassociation
+func isMaintainer(authorAssociation string) bool {
+	switch authorAssociation {
+	case "OWNER", "MEMBER", "COLLABORATOR":
+		return true
+	}
+	return false
+}
+
+// the references also include open PRs that will close the issue once merged
+func getFixedBy(issue issue) []LinkedPR {
+	var fixedBy []LinkedPR
+	for _, pr := range issue.ClosedByPullRequestsReferences.Nodes {
+		if pr.Merged {
+			fixedBy = append(fixedBy, LinkedPR{Number: pr.Number, URL: pr.URL, MergedAt: pr.MergedAt})
+		}
+	}
+	return fixedBy
+}
+
+func getIssueDetails(issue issue, now time.Time) IssueDetails {
+	resolutionTime := -1
+	if issue.State == "CLOSED" {
+		resolutionTime = int(issue.ClosedAt.Sub(issue.CreatedAt).Seconds())
+	}
+
+	var assignees []string
+	for _, assignee := range issue.Assignees.Nodes {
+		assignees = append(assignees, assignee.Login)
+	}
+	var labels []string
+	for _, label := range issue.Labels.Nodes {
+		labels = append(labels, label.Name)
+	}
+
+	details := IssueDetails{
+		Number:            issue.Number,
+		Title:             issue.Title,
+		URL:               issue.URL,
+		ResolutionTime:    resolutionTime,
+		State:             issue.State,
+		StateReason:       getStateReason(issue),
+		Age:               int(now.Sub(issue.CreatedAt).Seconds()),
+		LastActivity:      issue.UpdatedAt,
+		Assignees:         assignees,
+		Labels:            labels,
+		Association:       issue.AuthorAssociation,
+		FixedBy:           getFixedBy(issue),
+		FirstResponseTime: -1,
+		FirstLabelTime:    -1,
+		FirstAssigneeTime: -1,
+	}
+	if response := getFirstMaintainerResponse(issue); response != nil {
+		details.FirstResponseTime = int(response.CreatedAt.Sub(issue.CreatedAt).Seconds())
+	}
+	if len(issue.FirstLabel.Nodes) > 0 {
+		details.FirstLabelTime = int(issue.FirstLabel.Nodes[0].CreatedAt.Sub(issue.CreatedAt).Seconds())
+	}
+	if len(issue.FirstAssignment.Nodes) > 0 {
+		details.FirstAssigneeTime = int(issue.FirstAssignment.Nodes[0].CreatedAt.Sub(issue.CreatedAt).Seconds())
+	}
+	return details
+}
+
+// returns the details of open issues, ordered by the given ordering (age or lastActivity, see issueDetailsLess)
+func GetOpenIssues(issues []issue, sortBy string, now time.Time) []IssueDetails {
+	details := []IssueDetails{}
+	for _, issue := range issues {
+		details = append(details, getIssueDetails(issue, now))
+	}
+	if less := issueDetailsLess[sortBy]; less != nil {
+		sort.SliceStable(details, func(i, j int) bool {
+			return less(details[i], details[j])
+		})
+	}
+	return details
+}
+
+// sorts the details of each week by the given ordering (see issueDetailsLess) and keeps at most limit of them, if
+// limit is positive
+func SortIssueDetails(metrics []WeeklyIssueMetrics, sortBy string, limit int) {
+	less := issueDetailsLess[sortBy]
+	for i := range metrics {
+		details := metrics[i].Details
+		if less != nil {
+			sort.SliceStable(details, func(a, b int) bool {
+				return less(details[a], details[b])
+			})
+		}
+		if limit > 0 && len(details) > limit {
+			metrics[i].Details = details[:limit]
+		}
+	}
+}
+
+// issues closed before close reasons were introduced have no reason
+func getStateReason(issue issue) string {
+	if issue.StateReason == "" && issue.State == "CLOSED" {
+		return "COMPLETED"
+	}
+	return issue.StateReason
+}
+
+// undoes every open, close and reopen that happened after the given time to get the number of issues open at that time
+func getBacklogAt(issues []issue, numOpen int, at time.Time) int {
+	if numOpen < 0 {
+		return -1
+	}
+	backlog := numOpen
+	for _, issue := range issues {
+		isOpen := issue.State != "CLOSED"
+		wasOpen := !issue.CreatedAt.After(at) && !wasClosedAt(issue, at)
+		if isOpen && !wasOpen {
+			backlog--
+		} else if !isOpen && wasOpen {
+			backlog++
+		}
+	}
+	return backlog
+}
+
+// whether an issue created before the given time was closed at that time. only the latest close is fetched, so an
+// issue reopened after the given time is taken to have been closed then
+func wasClosedAt(issue issue, at time.Time) bool {
+	for _, reopened := range issue.ReopenedEvents.Nodes {
+		if reopened.CreatedAt.After(at) {
+			return true
+		}
+	}
+	return issue.State == "CLOSED" && !issue.ClosedAt.After(at)
+}
+
 func GetPRScore(prs []pr, since time.Time, numWeeks int) []WeeklyPRMetrics {
 	weekToNumPRsOpened := map[int]int{}
 	weekToNumPRsMerged := map[int]int{}
 	weekToNumPRsRejected := map[int]int{}
 	weekToPRDetails := map[int][]PRDetails{}
+	weekToCycleTimes := map[int][]PRCycleTime{}
+	weekToCompliance := map[int]PRCompliance{}
 	secondsInWeek := 60 * 60 * 24 * 7
 	for _, pr := range prs {
 		createdWeek := int(pr.CreatedAt.Sub(since).Seconds()) / secondsInWeek
 		weekToNumPRsOpened[createdWeek]++
 
+		numApprovals, hasChangesRequested := getPRReviewDecisions(pr)
 		resolutionTime := -1
 		if pr.ClosedAt.After(since) {
 			closedWeek := int(pr.ClosedAt.Sub(since).Seconds()) / secondsInWeek
 			if pr.Merged {
 				weekToNumPRsMerged[closedWeek]++
+				compliance := weekToCompliance[closedWeek]
+				if numApprovals == 0 {
+					compliance.NumMergedWithoutApproval++
+				}
+				if pr.MergedBy.Login != "" && pr.MergedBy.Login == pr.Author.Login {
+					compliance.NumSelfMerged++
+				}
+				if hasChangesRequested {
+					compliance.NumMergedWithChangesRequested++
+				}
+				weekToCompliance[closedWeek] = compliance
 			} else {
 				weekToNumPRsRejected[closedWeek]++
 			}
 			resolutionTime = int(pr.ClosedAt.Sub(pr.CreatedAt).Seconds())
 		}
 
-		reviewTime := -1
-		for _, review := range pr.Reviews.Nodes {
-			if review.Author.Login != pr.Author.Login {
-				reviewTime = int(review.CreatedAt.Sub(pr.CreatedAt).Seconds())
-				break
-			}
-		}
-
+		reviewTime := getReviewTime(pr)
+		cycleTime := getPRCycleTime(pr)
+		weekToCycleTimes[createdWeek] = append(weekToCycleTimes[createdWeek], cycleTime)
 		weekToPRDetails[createdWeek] = append(weekToPRDetails[createdWeek], PRDetails{
 			Number:         pr.Number,
 			Title:          pr.Title,
@@ -125,6 +565,10 @@ func GetPRScore(prs []pr, since time.Time, numWeeks int) []WeeklyPRMetrics {
 			ResolutionTime: resolutionTime,
 			ReviewTime:     reviewTime,
 			NumReviews:     pr.Reviews.TotalCount,
+			CycleTime:      cycleTime,
+			NumApprovals:   numApprovals,
+			MergedBy:       pr.MergedBy.Login,
+			Association:    pr.AuthorAssociation,
 		})
 	}
 
@@ -135,6 +579,8 @@ func GetPRScore(prs []pr, since time.Time, numWeeks int) []WeeklyPRMetrics {
 			NumOpen:     weekToNumPRsOpened[week],
 			NumRejected: weekToNumPRsRejected[week],
 			NumMerged:   weekToNumPRsMerged[week],
+			CycleTime:   getMedianCycleTime(weekToCycleTimes[week]),
+			Compliance:  weekToCompliance[week],
 			Details:     weekToPRDetails[week],
 		})
 	}
@@ -142,37 +588,284 @@ func GetPRScore(prs []pr, since time.Time, numWeeks int) []WeeklyPRMetrics {
 	return prMetrics
 }
 
-func GetCIScore(prs []pr, since time.Time, numWeeks int) []WeeklyCIMetrics {
-	weekToCIDetails := map[int][]CIDetails{}
+// time from creation to the first review by someone other than the author, -1 if there is none
+func getReviewTime(pr pr) int {
+	for _, review := range pr.Reviews.Nodes {
+		if review.Author.Login != pr.Author.Login {
+			return int(review.CreatedAt.Sub(pr.CreatedAt).Seconds())
+		}
+	}
+	return -1
+}
+
+// returns the number of approvals from reviewers other than the author, and whether any reviewer's latest review
+// still requests changes. approvals that were dismissed or superseded by a later review don't count
+func getPRReviewDecisions(pr pr) (int, bool) {
+	numApprovals := 0
+	hasChangesRequested := false
+	for _, review := range pr.LatestOpinionatedReviews.Nodes {
+		if review.Author.Login == pr.Author.Login {
+			continue
+		}
+		switch review.State {
+		case "APPROVED":
+			numApprovals++
+		case "CHANGES_REQUESTED":
+			hasChangesRequested = true
+		}
+	}
+	return numApprovals, hasChangesRequested
+}
+
+func getPRCycleTime(pr pr) PRCycleTime {
+	cycleTime := PRCycleTime{Coding: -1, Pickup: -1, Review: -1, Merge: -1}
+
+	if len(pr.FirstCommit.Nodes) > 0 {
+		// commits can be authored after the PR is opened, e.g. for PRs opened from an empty branch
+		cycleTime.Coding = int(pr.CreatedAt.Sub(pr.FirstCommit.Nodes[0].Commit.AuthoredDate).Seconds())
+		if cycleTime.Coding < 0 {
+			cycleTime.Coding = 0
+		}
+	}
+
+	var firstReview, firstApproval time.Time
+	for _, review := range pr.Reviews.Nodes {
+		if review.Author.Login != pr.Author.Login {
+			firstReview = review.CreatedAt
+			break
+		}
+	}
+	if len(pr.FirstApproval.Nodes) > 0 {
+		firstApproval = pr.FirstApproval.Nodes[0].CreatedAt
+		// the approval is itself a review, which may be past the fetched ones
+		if firstReview.IsZero() || firstReview.After(firstApproval) {
+			firstReview = firstApproval
+		}
+	}
+
+	if !firstReview.IsZero() {
+		cycleTime.Pickup = int(firstReview.Sub(pr.CreatedAt).Seconds())
+	}
+	if !firstApproval.IsZero() {
+		cycleTime.Review = int(firstApproval.Sub(firstReview).Seconds())
+		if pr.Merged {
+			cycleTime.Merge = int(pr.MergedAt.Sub(firstApproval).Seconds())
+		}
+	}
+	return cycleTime
+}
+
+func getMedianCycleTime(cycleTimes []PRCycleTime) PRCycleTime {
+	var coding, pickup, review, merge []int
+	for _, cycleTime := range cycleTimes {
+		coding = appendIfSet(coding, cycleTime.Coding)
+		pickup = appendIfSet(pickup, cycleTime.Pickup)
+		review = appendIfSet(review, cycleTime.Review)
+		merge = appendIfSet(merge, cycleTime.Merge)
+	}
+	return PRCycleTime{
+		Coding: percentile(coding, 50),
+		Pickup: percentile(pickup, 50),
+		Review: percentile(review, 50),
+		Merge:  percentile(merge, 50),
+	}
+}
+
+// durations of -1 mean the event has not happened yet and are excluded from aggregates
+func appendIfSet(values []int, value int) []int {
+	if value < 0 {
+		return values
+	}
+	return append(values, value)
+}
+
+func getPercentiles(values []int) Percentiles {
+	return Percentiles{
+		P50: percentile(values, 50),
+		P90: percentile(values, 90),
+	}
+}
+
+// returns the nearest-rank percentile of the values, or -1 if there are none
+func percentile(values []int, p float64) int {
+	if len(values) == 0 {
+		return -1
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// groups PRs by author login and scores each group separately. if authors is non-empty, only those logins are
+// returned (including ones without any PRs in the window)
+func GetPRScoreByAuthor(prs []pr, since time.Time, numWeeks int, authors []string) []AuthorPRMetrics {
+	authorToPRs := map[string][]pr{}
+	requested := map[string]string{}
+	for _, author := range authors {
+		requested[strings.ToLower(author)] = author
+		authorToPRs[author] = nil
+	}
 
-	secondsInWeek := 60 * 60 * 24 * 7
 	for _, pr := range prs {
-		createdWeek := int(pr.CreatedAt.Sub(since).Seconds()) / secondsInWeek
-		latestPRCommit := pr.Commits.Nodes[0].Commit
-		var statusStartDate time.Time
-		if pr.IsCrossRepository {
-			// pushed date is unavailable for PRs made from forks; use the commit date instead
-			if pr.CreatedAt.After(latestPRCommit.CommittedDate) {
-				// first commit; build isn't triggered until PR creation so use that date
-				statusStartDate = pr.CreatedAt
-			} else {
-				// not ideal in case commit is made awhile before pushing. however, updatedAt also includes comments,
-				// reviews, etc. can possibly traverse PR timeline to get a more accurate date
-				statusStartDate = latestPRCommit.CommittedDate
+		login := pr.Author.Login
+		if login == "" {
+			// author is null for deleted accounts
+			login = "ghost"
+		}
+		if len(requested) > 0 {
+			author, ok := requested[strings.ToLower(login)]
+			if !ok {
+				continue
+			}
+			login = author
+		}
+		authorToPRs[login] = append(authorToPRs[login], pr)
+	}
+
+	var logins []string
+	for login := range authorToPRs {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+
+	authorMetrics := []AuthorPRMetrics{}
+	for _, login := range logins {
+		authorMetrics = append(authorMetrics, AuthorPRMetrics{
+			Author:  login,
+			Metrics: GetPRScore(authorToPRs[login], since, numWeeks),
+		})
+	}
+	return authorMetrics
+}
+
+// returns open PRs ordered by urgency: PRs waiting on reviewers come first, then PRs waiting on their author, each
+// ordered by time since last activity
+func GetStalePRs(prs []pr, now time.Time) []StalePRDetails {
+	stalePRs := []StalePRDetails{}
+	for _, pr := range prs {
+		var requestedReviewers []string
+		for _, request := range pr.ReviewRequests.Nodes {
+			if request.RequestedReviewer.Login != "" {
+				requestedReviewers = append(requestedReviewers, request.RequestedReviewer.Login)
+			} else if request.RequestedReviewer.Slug != "" {
+				requestedReviewers = append(requestedReviewers, request.RequestedReviewer.Slug)
 			}
-		} else {
-			statusStartDate = latestPRCommit.PushedDate
 		}
 
-		maxCheckDuration := 0
-		var maxCheckContext checkContext
-		for _, context := range latestPRCommit.Status.Contexts {
-			duration := int(context.CreatedAt.Sub(statusStartDate).Seconds())
-			if duration > maxCheckDuration {
-				maxCheckDuration = duration
-				maxCheckContext = context
+		stalePRs = append(stalePRs, StalePRDetails{
+			Number:             pr.Number,
+			Title:              pr.Title,
+			URL:                pr.URL,
+			Author:             pr.Author.Login,
+			Draft:              pr.IsDraft,
+			Age:                int(now.Sub(pr.CreatedAt).Seconds()),
+			Idle:               int(now.Sub(pr.UpdatedAt).Seconds()),
+			Status:             getOpenPRStatus(pr, len(requestedReviewers) > 0),
+			RequestedReviewers: requestedReviewers,
+		})
+	}
+
+	sort.SliceStable(stalePRs, func(i, j int) bool {
+		if stalePRs[i].Status != stalePRs[j].Status {
+			return statusUrgency[stalePRs[i].Status] < statusUrgency[stalePRs[j].Status]
+		}
+		return stalePRs[i].Idle > stalePRs[j].Idle
+	})
+	return stalePRs
+}
+
+func getOpenPRStatus(pr pr, hasPendingRequests bool) string {
+	if pr.IsDraft {
+		return statusAwaitingAuthor
+	}
+
+	var lastPush time.Time
+	if len(pr.Commits.Nodes) > 0 {
+		lastPush = pr.Commits.Nodes[0].Commit.PushedDate
+		if lastPush.IsZero() {
+			// pushed date is unavailable for PRs made from forks
+			lastPush = pr.Commits.Nodes[0].Commit.CommittedDate
+		}
+	}
+
+	approved := false
+	for _, review := range pr.LatestOpinionatedReviews.Nodes {
+		switch review.State {
+		case "CHANGES_REQUESTED":
+			if review.SubmittedAt.After(lastPush) {
+				return statusAwaitingAuthor
 			}
+		case "APPROVED":
+			approved = true
 		}
+	}
+	if approved && !hasPendingRequests {
+		return statusApproved
+	}
+	return statusAwaitingReview
+}
+
+// scores PRs opened by maintainers and by the community separately
+func GetPRScoreByAssociation(prs []pr, since time.Time, numWeeks int) []AssociationPRMetrics {
+	groupToPRs := map[string][]pr{}
+	for _, pr := range prs {
+		group := getAuthorGroup(pr.AuthorAssociation)
+		groupToPRs[group] = append(groupToPRs[group], pr)
+	}
+
+	associationMetrics := []AssociationPRMetrics{}
+	for _, group := range []string{authorGroupMaintainer, authorGroupCommunity} {
+		associationMetrics = append(associationMetrics, AssociationPRMetrics{
+			Association: group,
+			Metrics:     GetPRScore(groupToPRs[group], since, numWeeks),
+		})
+	}
+	return associationMetrics
+}
+
+func GetReviewScore(reviews []review, since time.Time, numWeeks int) []WeeklyReviewMetrics {
+	weekToReviewDetails := map[int][]ReviewDetails{}
+
+	secondsInWeek := 60 * 60 * 24 * 7
+	for _, review := range reviews {
+		if review.OccurredAt.Before(since) {
+			continue
+		}
+		week := int(review.OccurredAt.Sub(since).Seconds()) / secondsInWeek
+		weekToReviewDetails[week] = append(weekToReviewDetails[week], ReviewDetails{
+			PR:       review.PullRequest.Number,
+			PRURL:    review.PullRequest.URL,
+			Repo:     review.PullRequest.Repository.NameWithOwner,
+			Author:   review.PullRequest.Author.Login,
+			Reviewer: review.PullRequestReview.Author.Login,
+			State:    review.PullRequestReview.State,
+		})
+	}
+
+	reviewMetrics := []WeeklyReviewMetrics{}
+	for week := 0; week < numWeeks; week++ {
+		reviewMetrics = append(reviewMetrics, WeeklyReviewMetrics{
+			Week:       since.AddDate(0, 0, week*7).Format(dateFormat),
+			NumReviews: len(weekToReviewDetails[week]),
+			Details:    weekToReviewDetails[week],
+		})
+	}
+
+	return reviewMetrics
+}
+
+func GetCIScore(prs []pr, since time.Time, numWeeks int) []WeeklyCIMetrics {
+	weekToCIDetails := map[int][]CIDetails{}
+
+	secondsInWeek := 60 * 60 * 24 * 7
+	for _, pr := range prs {
+		createdWeek := int(pr.CreatedAt.Sub(since).Seconds()) / secondsInWeek
+		statusStartDate := getStatusStartDate(pr)
+		maxCheckContext, maxCheckDuration := getMaxCheck(pr, statusStartDate)
 		statusStartWeek := int(statusStartDate.Sub(since).Seconds()) / secondsInWeek
 		if statusStartWeek < 0 {
 			// commit may have been pushed before the PR was created; in this case use the PR creation date
@@ -184,6 +877,7 @@ func GetCIScore(prs []pr, since time.Time, numWeeks int) []WeeklyCIMetrics {
 			MaxCheckName:     maxCheckContext.Context,
 			MaxCheckDuration: maxCheckDuration,
 			MaxCheckURL:      maxCheckContext.TargetURL,
+			NumChecks:        len(pr.Commits.Nodes[0].Commit.Status.Contexts),
 		})
 	}
 
@@ -197,3 +891,45 @@ func GetCIScore(prs []pr, since time.Time, numWeeks int) []WeeklyCIMetrics {
 
 	return ciMetrics
 }
+
+// returns the slowest check duration of each PR that ran any checks, leaving out PRs without CI rather than counting
+// them as instant
+func getCIDurations(details []CIDetails) []int {
+	var durations []int
+	for _, pr := range details {
+		if pr.NumChecks > 0 {
+			durations = append(durations, pr.MaxCheckDuration)
+		}
+	}
+	return durations
+}
+
+// returns when the checks on the PR's latest commit started
+func getStatusStartDate(pr pr) time.Time {
+	latestPRCommit := pr.Commits.Nodes[0].Commit
+	if pr.IsCrossRepository {
+		// pushed date is unavailable for PRs made from forks; use the commit date instead
+		if pr.CreatedAt.After(latestPRCommit.CommittedDate) {
+			// first commit; build isn't triggered until PR creation so use that date
+			return pr.CreatedAt
+		}
+		// not ideal in case commit is made awhile before pushing. however, updatedAt also includes comments,
+		// reviews, etc. can possibly traverse PR timeline to get a more accurate date
+		return latestPRCommit.CommittedDate
+	}
+	return latestPRCommit.PushedDate
+}
+
+// returns the check on the PR's latest commit that took the longest, and its duration in sec
+func getMaxCheck(pr pr, statusStartDate time.Time) (checkContext, int) {
+	maxCheckDuration := 0
+	var maxCheckContext checkContext
+	for _, context := range pr.Commits.Nodes[0].Commit.Status.Contexts {
+		duration := int(context.CreatedAt.Sub(statusStartDate).Seconds())
+		if duration > maxCheckDuration {
+			maxCheckDuration = duration
+			maxCheckContext = context
+		}
+	}
+	return maxCheckContext, maxCheckDuration
+}
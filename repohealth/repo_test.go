@@ -0,0 +1,55 @@
+package repohealth
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// decodes data shaped like the GraphQL response into v
+func unmarshal(t *testing.T, data string, v interface{}) {
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetPRScoreIgnoresSupersededApprovals(t *testing.T) {
+	since := time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC)
+	var merged pr
+	unmarshal(t, `{
+		"createdAt": "2026-10-05T00:00:00Z",
+		"closedAt": "2026-10-07T00:00:00Z",
+		"mergedAt": "2026-10-07T00:00:00Z",
+		"merged": true,
+		"author": {"login": "author"},
+		"mergedBy": {"login": "reviewer"},
+		"reviews": {"nodes": [{"createdAt": "2026-10-05T01:00:00Z", "state": "APPROVED", "author": {"login": "reviewer"}}]},
+		"firstApproval": {"nodes": [{"createdAt": "2026-10-05T01:00:00Z"}]},
+		"latestOpinionatedReviews": {"nodes": [{"state": "CHANGES_REQUESTED", "submittedAt": "2026-10-06T00:00:00Z", "author": {"login": "reviewer"}}]}
+	}`, &merged)
+
+	week := GetPRScore([]pr{merged}, since, 1)[0]
+	if week.Details[0].NumApprovals != 0 {
+		t.Errorf("got %d approvals, want 0", week.Details[0].NumApprovals)
+	}
+	want := PRCompliance{NumMergedWithoutApproval: 1, NumMergedWithChangesRequested: 1}
+	if week.Compliance != want {
+		t.Errorf("got %+v, want %+v", week.Compliance, want)
+	}
+}
+
+func TestGetBacklogAtUndoesReopens(t *testing.T) {
+	at := time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)
+	var issues []issue
+	unmarshal(t, `[
+		{"state": "OPEN", "createdAt": "2026-10-01T00:00:00Z", "reopenedEvents": {"nodes": [{"createdAt": "2026-10-12T00:00:00Z"}]}},
+		{"state": "CLOSED", "createdAt": "2026-10-01T00:00:00Z", "closedAt": "2026-10-13T00:00:00Z", "reopenedEvents": {"nodes": [{"createdAt": "2026-10-12T00:00:00Z"}]}},
+		{"state": "OPEN", "createdAt": "2026-10-01T00:00:00Z", "reopenedEvents": {"nodes": [{"createdAt": "2026-10-03T00:00:00Z"}]}}
+	]`, &issues)
+
+	// the first was closed at the time and reopened after, the second was also closed again after, and the third was
+	// reopened before the time so has been open since
+	if backlog := getBacklogAt(issues, 2, at); backlog != 1 {
+		t.Errorf("got a backlog of %d, want 1", backlog)
+	}
+}
@@ -0,0 +1,276 @@
+package repohealth
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/pkg/errors"
+)
+
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+)
+
+// enough for the latest complete week, the one before it, and the current partial week
+const reportWeeks = 3
+
+const numSlowestPRs = 5
+
+type Report struct {
+	Week        string // start of the latest complete week
+	GeneratedAt time.Time
+	Repos       []RepoReport
+}
+
+type RepoReport struct {
+	Repo       string
+	Metrics    []ReportMetric
+	SlowestPRs []PRDetails // resolved in the window, slowest first
+}
+
+// values are -1 if there was no data
+type ReportMetric struct {
+	Name       string
+	Current    float64
+	Previous   float64
+	IsDuration bool // in sec
+}
+
+// fetches the repos (owner/name) and builds a report comparing the latest complete week to the week before
+func BuildReport(authHeader string, repos []string, now time.Time) (Report, error) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+	since := getStartDate(reportWeeks)
+
+	data := make([]repoData, len(repos))
+	err := forEachParallel(len(repos), maxParallelism, func(i int) error {
+		owner, name, ok := splitRepo(repos[i])
+		if !ok {
+			return errors.Errorf("invalid repo %q, expected owner/name", repos[i])
+		}
+		repo, err := getRepoData(client, authHeader, owner, name, since, prFilter{})
+		data[i] = repo
+		return errors.Wrap(err, repos[i])
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	// the latest week that has ended
+	current := reportWeeks - 1
+	for current > 0 && since.AddDate(0, 0, (current+1)*7).After(now) {
+		current--
+	}
+
+	report := Report{Week: since.AddDate(0, 0, current*7).Format(dateFormat), GeneratedAt: now}
+	for _, repo := range data {
+		report.Repos = append(report.Repos, getRepoReport(repo, since, current))
+	}
+	return report, nil
+}
+
+func getRepoReport(repo repoData, since time.Time, current int) RepoReport {
+	issues := GetIssueScore(repo.Issues, repo.NumOpen, since, reportWeeks, false)
+	prs := GetPRScore(repo.PRs, since, reportWeeks)
+	ci := GetCIScore(repo.CIPRs, since, reportWeeks)
+
+	metric := func(name string, values []float64, isDuration bool) ReportMetric {
+		previous := -1.0
+		if current > 0 {
+			previous = values[current-1]
+		}
+		return ReportMetric{Name: name, Current: values[current], Previous: previous, IsDuration: isDuration}
+	}
+	count := func(value func(week int) int) []float64 {
+		var values []float64
+		for week := 0; week < reportWeeks; week++ {
+			values = append(values, float64(value(week)))
+		}
+		return values
+	}
+
+	var slowestPRs []PRDetails
+	for _, week := range prs {
+		for _, details := range week.Details {
+			if details.ResolutionTime >= 0 {
+				slowestPRs = append(slowestPRs, details)
+			}
+		}
+	}
+	sort.SliceStable(slowestPRs, func(i, j int) bool {
+		return slowestPRs[i].ResolutionTime > slowestPRs[j].ResolutionTime
+	})
+	if len(slowestPRs) > numSlowestPRs {
+		slowestPRs = slowestPRs[:numSlowestPRs]
+	}
+
+	return RepoReport{
+		Repo: repo.Repo,
+		Metrics: []ReportMetric{
+			metric("Issues opened", count(func(week int) int { return issues[week].NumOpen }), false),
+			metric("Issues closed", count(func(week int) int { return issues[week].NumClosed }), false),
+			metric("Issue backlog", count(func(week int) int { return issues[week].NumBacklog }), false),
+			metric("Median issue resolution time", indicators[indicatorMedianIssueResolutionTime](issues, prs, ci), true),
+			metric("PRs opened", count(func(week int) int { return prs[week].NumOpen }), false),
+			metric("PRs merged", count(func(week int) int { return prs[week].NumMerged }), false),
+			metric("Median review time", indicators[indicatorMedianReviewTime](issues, prs, ci), true),
+			metric("CI p90", indicators[indicatorCIP90](issues, prs, ci), true),
+		},
+		SlowestPRs: slowestPRs,
+	}
+}
+
+var reportFuncs = map[string]interface{}{
+	"value":    formatReportValue,
+	"change":   formatReportChange,
+	"duration": formatDuration,
+	"markdown": escapeMarkdown,
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`,
+	"\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// escapes text such as PR titles so that it can't break out of a table cell or link text
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// writes the report in the given format, using the configured template if there is one
+func WriteReport(w io.Writer, report Report, format string, config ReportConfig) error {
+	switch format {
+	case ReportFormatMarkdown:
+		text, err := getReportTemplate(config.MarkdownTemplate, markdownReportTemplate)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New("report").Funcs(reportFuncs).Parse(text)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse markdown report template")
+		}
+		return tmpl.Execute(w, report)
+	case ReportFormatHTML:
+		text, err := getReportTemplate(config.HTMLTemplate, htmlReportTemplate)
+		if err != nil {
+			return err
+		}
+		tmpl, err := htmltemplate.New("report").Funcs(reportFuncs).Parse(text)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse html report template")
+		}
+		return tmpl.Execute(w, report)
+	default:
+		return errors.Errorf("unknown report format %q", format)
+	}
+}
+
+func getReportTemplate(path string, defaultTemplate string) (string, error) {
+	if path == "" {
+		return defaultTemplate, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read report template")
+	}
+	return string(data), nil
+}
+
+func formatReportValue(value float64, isDuration bool) string {
+	if value < 0 {
+		return "n/a"
+	}
+	if isDuration {
+		return formatDuration(int(value))
+	}
+	return fmt.Sprintf("%g", value)
+}
+
+func formatReportChange(metric ReportMetric) string {
+	if metric.Current < 0 || metric.Previous <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.0f%%", (metric.Current-metric.Previous)/metric.Previous*100)
+}
+
+// formats seconds as e.g. "2d 3h", "6h", "15m" or "40s", keeping the two largest units
+func formatDuration(sec int) string {
+	if sec < 0 {
+		return "n/a"
+	}
+	units := []struct {
+		name string
+		sec  int
+	}{{"d", 24 * 60 * 60}, {"h", 60 * 60}, {"m", 60}, {"s", 1}}
+
+	formatted := ""
+	for i, unit := range units {
+		if sec < unit.sec && !(unit.sec == 1 && formatted == "") {
+			continue
+		}
+		formatted = fmt.Sprintf("%d%s", sec/unit.sec, unit.name)
+		if rest := sec % unit.sec; rest > 0 && i+1 < len(units) && rest >= units[i+1].sec {
+			formatted += fmt.Sprintf(" %d%s", rest/units[i+1].sec, units[i+1].name)
+		}
+		break
+	}
+	return formatted
+}
+
+const markdownReportTemplate = `# Weekly health report
+
+Week of {{.Week}}, generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}.
+{{range .Repos}}
+## {{.Repo}}
+
+| Metric | This week | Last week | Change |
+| --- | --- | --- | --- |
+{{range .Metrics}}| {{.Name}} | {{value .Current .IsDuration}} | {{value .Previous .IsDuration}} | {{change .}} |
+{{end}}{{if .SlowestPRs}}
+### Slowest PRs
+
+{{range .SlowestPRs}}- [#{{.Number}} {{markdown .Title}}]({{.URL}}): {{duration .ResolutionTime}}
+{{end}}{{end}}{{end}}`
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Weekly health report</title>
+<style>
+	body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+	table { border-collapse: collapse; margin-bottom: 1em; }
+	th, td { border: 1px solid #d1d5da; padding: 4px 12px; text-align: right; }
+	th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Weekly health report</h1>
+<p>Week of {{.Week}}, generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}.</p>
+{{range .Repos}}
+<h2>{{.Repo}}</h2>
+<table>
+	<tr><th>Metric</th><th>This week</th><th>Last week</th><th>Change</th></tr>
+	{{range .Metrics}}
+	<tr><td>{{.Name}}</td><td>{{value .Current .IsDuration}}</td><td>{{value .Previous .IsDuration}}</td><td>{{change .}}</td></tr>
+	{{end}}
+</table>
+{{if .SlowestPRs}}
+<h3>Slowest PRs</h3>
+<ul>
+	{{range .SlowestPRs}}
+	<li><a href="{{.URL}}">#{{.Number}} {{.Title}}</a>: {{duration .ResolutionTime}}</li>
+	{{end}}
+</ul>
+{{end}}
+{{end}}
+</body>
+</html>
+`
@@ -0,0 +1,27 @@
+package repohealth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteReportEscapesMarkdownTitles(t *testing.T) {
+	report := Report{
+		Week:        "2026-10-04",
+		GeneratedAt: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		Repos: []RepoReport{{
+			Repo:       "o/r",
+			SlowestPRs: []PRDetails{{Number: 1, Title: "Fix a|b [draft]\nfollow-up", URL: "https://github.com/o/r/pull/1", ResolutionTime: 60}},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, report, ReportFormatMarkdown, ReportConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `- [#1 Fix a\|b \[draft\] follow-up](https://github.com/o/r/pull/1): 1m` + "\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("got report\n%s\nwant it to contain\n%s", buf.String(), want)
+	}
+}
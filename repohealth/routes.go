@@ -1,7 +1,6 @@
 package repohealth
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -10,15 +9,71 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/machinebox/graphql"
+	"github.com/pkg/errors"
 )
 
 func getWeeks(r *http.Request) int {
-	numWeeks, err := strconv.Atoi(r.URL.Query().Get("weeks"))
+	return getIntParam(r, "weeks", 6)
+}
+
+func getIntParam(r *http.Request, key string, defaultValue int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
 	if err != nil {
-		log.Println("failed to parse weeks parameter, using default of 6", err)
-		return 6
+		log.Printf("failed to parse %s parameter, using default of %d: %v\n", key, defaultValue, err)
+		return defaultValue
+	}
+	return value
+}
+
+// returns the comma-separated values of the given query parameter, ignoring empty entries
+func getListParam(r *http.Request, key string) []string {
+	var values []string
+	for _, value := range strings.Split(r.URL.Query().Get(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getLabelFilter(r *http.Request) labelFilter {
+	return labelFilter{
+		Labels:        getListParam(r, "labels"),
+		ExcludeLabels: getListParam(r, "excludeLabels"),
+	}
+}
+
+func getRepoFilter(r *http.Request) repoFilter {
+	return repoFilter{
+		Include:  getListParam(r, "repos"),
+		Exclude:  getListParam(r, "excludeRepos"),
+		Archived: r.URL.Query().Get("archived") == "true",
+		Forks:    r.URL.Query().Get("forks") == "true",
+		Topics:   getListParam(r, "topics"),
 	}
-	return numWeeks
+}
+
+// head is a glob pattern for the head branch, where * also matches /, e.g. head=hotfix* matches hotfix/login
+func getPRFilter(r *http.Request) (prFilter, error) {
+	filter := prFilter{
+		labelFilter: getLabelFilter(r),
+		BaseBranch:  r.URL.Query().Get("base"),
+		Authors:     getListParam(r, "authors"),
+		HeadBranch:  r.URL.Query().Get("head"),
+	}
+	if _, err := matchBranch(filter.HeadBranch, ""); err != nil {
+		return filter, errors.Wrapf(err, "invalid head branch pattern %q", filter.HeadBranch)
+	}
+	return filter, nil
+}
+
+// splits owner/name, ok is false if repo is not in that form
+func splitRepo(repo string) (owner string, name string, ok bool) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
 }
 
 // returns the first Sunday after (today - numWeeks)
@@ -30,9 +85,19 @@ func getStartDate(numWeeks int) time.Time {
 	return since
 }
 
+// search qualifiers for the open issues in a label group, where the empty label is the group of unlabeled issues
+func labelGroupQualifiers(label string, filter labelFilter) string {
+	groupFilter := labelFilter{ExcludeLabels: filter.ExcludeLabels}
+	if label == "" {
+		return "no:label " + groupFilter.searchQualifiers()
+	}
+	groupFilter.Labels = []string{label}
+	return groupFilter.searchQualifiers()
+}
+
 func handleError(err error, w http.ResponseWriter) {
 	log.Println(err)
-	if strings.Contains(err.Error(), "Could not resolve to a Repository") {
+	if strings.Contains(err.Error(), "Could not resolve to a") {
 		w.WriteHeader(http.StatusNotFound)
 	} else {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -45,14 +110,89 @@ func GetRepositoryIssues(w http.ResponseWriter, r *http.Request, params httprout
 	authHeader := r.Header.Get("Authorization")
 	numWeeks := getWeeks(r)
 	since := getStartDate(numWeeks)
+	sortBy := r.URL.Query().Get("sort")
+	if _, ok := issueDetailsLess[sortBy]; sortBy != "" && !ok {
+		log.Println("invalid sort parameter", sortBy)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	owner, name := params.ByName("owner"), params.ByName("name")
+	filter := getLabelFilter(r)
+	excludeNotPlanned := r.URL.Query().Get("excludeNotPlanned") == "true"
+
+	issues, numOpen, err := getMatchingIssuesUpdatedSince(client, authHeader, owner, name, since, filter)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	switch r.URL.Query().Get("groupBy") {
+	case "association":
+		associationScore := GetIssueScoreByAssociation(issues, since, numWeeks, excludeNotPlanned)
+		for _, group := range associationScore {
+			SortIssueDetails(group.Metrics, sortBy, getIntParam(r, "limit", 0))
+		}
+		writeResponse(w, r, associationScore)
+		return
+	case "label":
+		labels := GetIssueLabels(issues, filter)
+		var qualifiers []string
+		for _, label := range labels {
+			qualifiers = append(qualifiers, labelGroupQualifiers(label, filter))
+		}
+		counts, err := getOpenIssueCounts(client, authHeader, owner, name, qualifiers)
+		if err != nil {
+			handleError(err, w)
+			return
+		}
+		labelToNumOpen := map[string]int{}
+		for i, label := range labels {
+			labelToNumOpen[label] = counts[i]
+		}
+		labelScore := GetIssueScoreByLabel(issues, labelToNumOpen, since, numWeeks, excludeNotPlanned)
+		for _, group := range labelScore {
+			SortIssueDetails(group.Metrics, sortBy, getIntParam(r, "limit", 0))
+		}
+		writeResponse(w, r, labelScore)
+		return
+	}
+
+	issueScore := GetIssueScore(issues, numOpen, since, numWeeks, excludeNotPlanned)
+	SortIssueDetails(issueScore, sortBy, getIntParam(r, "limit", 0))
+	writeResponse(w, r, issueScore)
+}
+
+// orders supported by GetRepositoryOpenIssues, with the field to fetch issues by so that the first pages are enough
+// to fill the limit
+var openIssueOrderFields = map[string]string{
+	"age":          "CREATED_AT",
+	"lastActivity": "UPDATED_AT",
+}
+
+// returns open issues regardless of when they were opened, by default the ones untouched for longest first
+func GetRepositoryOpenIssues(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+
+	authHeader := r.Header.Get("Authorization")
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "lastActivity"
+	}
+	orderField, ok := openIssueOrderFields[sortBy]
+	if !ok {
+		log.Println("invalid sort parameter", sortBy)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-	issues, err := getIssuesCreatedSince(client, authHeader, params.ByName("owner"), params.ByName("name"), since)
+	issues, err := getOpenIssues(client, authHeader, params.ByName("owner"), params.ByName("name"), orderField, getLabelFilter(r), getIntParam(r, "limit", 0))
 	if err != nil {
 		handleError(err, w)
 		return
 	}
-	issueScore := GetIssueScore(issues, since, getWeeks(r))
-	json.NewEncoder(w).Encode(issueScore)
+	openIssues := GetOpenIssues(issues, sortBy, time.Now())
+	writeResponse(w, r, openIssues)
 }
 
 func GetRepositoryPRs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -61,14 +201,52 @@ func GetRepositoryPRs(w http.ResponseWriter, r *http.Request, params httprouter.
 	authHeader := r.Header.Get("Authorization")
 	numWeeks := getWeeks(r)
 	since := getStartDate(numWeeks)
+	filter, err := getPRFilter(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-	prs, err := getRepoPRsCreatedSince(client, authHeader, params.ByName("owner"), params.ByName("name"), since, prFragment)
+	prs, err := getRepoPRsCreatedSince(client, authHeader, params.ByName("owner"), params.ByName("name"), since, prFragment, filter)
 	if err != nil {
 		handleError(err, w)
 		return
 	}
+	switch r.URL.Query().Get("groupBy") {
+	case "association":
+		associationScore := GetPRScoreByAssociation(prs, since, numWeeks)
+		writeResponse(w, r, associationScore)
+		return
+	case "author":
+		authorScore := GetPRScoreByAuthor(prs, since, numWeeks, filter.Authors)
+		writeResponse(w, r, authorScore)
+		return
+	}
 	prScore := GetPRScore(prs, since, numWeeks)
-	json.NewEncoder(w).Encode(prScore)
+	writeResponse(w, r, prScore)
+}
+
+func GetRepositoryStalePRs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+
+	authHeader := r.Header.Get("Authorization")
+	now := time.Now()
+	before := now.AddDate(0, 0, -getIntParam(r, "days", 7))
+	filter, err := getPRFilter(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	prs, err := getOpenPRsCreatedBefore(client, authHeader, params.ByName("owner"), params.ByName("name"), before, filter)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	stalePRs := GetStalePRs(prs, now)
+	writeResponse(w, r, stalePRs)
 }
 
 func GetRepositoryCI(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -77,14 +255,20 @@ func GetRepositoryCI(w http.ResponseWriter, r *http.Request, params httprouter.P
 	authHeader := r.Header.Get("Authorization")
 	numWeeks := getWeeks(r)
 	since := getStartDate(numWeeks)
+	filter, err := getPRFilter(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-	prs, err := getRepoPRsCreatedSince(client, authHeader, params.ByName("owner"), params.ByName("name"), since, prWithCIMetadataFragment)
+	prs, err := getRepoPRsCreatedSince(client, authHeader, params.ByName("owner"), params.ByName("name"), since, prWithCIMetadataFragment, filter)
 	if err != nil {
 		handleError(err, w)
 		return
 	}
 	ciScore := GetCIScore(prs, since, numWeeks)
-	json.NewEncoder(w).Encode(ciScore)
+	writeResponse(w, r, ciScore)
 }
 
 func GetUserPRs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -102,5 +286,279 @@ func GetUserPRs(w http.ResponseWriter, r *http.Request, params httprouter.Params
 		return
 	}
 	prScore := GetPRScore(prs, since, numWeeks)
-	json.NewEncoder(w).Encode(prScore)
+	writeResponse(w, r, prScore)
+}
+
+func GetUserProfile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	authHeader := r.Header.Get("Authorization")
+
+	profile, err := FetchUserProfile(authHeader, params.ByName("user"), r.URL.Query().Get("org"), getWeeks(r))
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeResponse(w, r, profile)
+}
+
+func GetOrgIssues(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+
+	authHeader := r.Header.Get("Authorization")
+	numWeeks := getWeeks(r)
+	since := getStartDate(numWeeks)
+	org := params.ByName("org")
+	filter := getLabelFilter(r)
+	excludeNotPlanned := r.URL.Query().Get("excludeNotPlanned") == "true"
+
+	repos, err := getOrgRepos(client, authHeader, org, getRepoFilter(r))
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	names := make([]string, len(repos))
+	repoIssues := make([][]issue, len(repos))
+	repoNumOpen := make([]int, len(repos))
+	err = forEachParallel(len(repos), maxParallelism, func(i int) error {
+		names[i] = repos[i].Name
+		issues, numOpen, err := getMatchingIssuesUpdatedSince(client, authHeader, org, repos[i].Name, since, filter)
+		repoIssues[i], repoNumOpen[i] = issues, numOpen
+		return errors.Wrap(err, repos[i].Name)
+	})
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	orgScore := GetOrgIssueScore(names, repoIssues, repoNumOpen, since, numWeeks, excludeNotPlanned)
+	writeResponse(w, r, orgScore)
+}
+
+func GetOrgPRs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	names, repoPRs, since, numWeeks, ok := getOrgPRs(w, r, params, prFragment)
+	if !ok {
+		return
+	}
+	orgScore := GetOrgPRScore(names, repoPRs, since, numWeeks)
+	writeResponse(w, r, orgScore)
+}
+
+func GetOrgCI(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	names, repoPRs, since, numWeeks, ok := getOrgPRs(w, r, params, prWithCIMetadataFragment)
+	if !ok {
+		return
+	}
+	orgScore := GetOrgCIScore(names, repoPRs, since, numWeeks)
+	writeResponse(w, r, orgScore)
+}
+
+// fetches the PRs of every matching repo in the org. if this fails the error is written to the response and ok is
+// false
+func getOrgPRs(w http.ResponseWriter, r *http.Request, params httprouter.Params, prFragment string) (names []string, repoPRs [][]pr, since time.Time, numWeeks int, ok bool) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+
+	authHeader := r.Header.Get("Authorization")
+	numWeeks = getWeeks(r)
+	since = getStartDate(numWeeks)
+	org := params.ByName("org")
+	filter, err := getPRFilter(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	repos, err := getOrgRepos(client, authHeader, org, getRepoFilter(r))
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	names = make([]string, len(repos))
+	repoPRs = make([][]pr, len(repos))
+	err = forEachParallel(len(repos), maxParallelism, func(i int) error {
+		names[i] = repos[i].Name
+		prs, err := getRepoPRsCreatedSince(client, authHeader, org, repos[i].Name, since, prFragment, filter)
+		repoPRs[i] = prs
+		return errors.Wrap(err, repos[i].Name)
+	})
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	return names, repoPRs, since, numWeeks, true
+}
+
+func GetTeamPRs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+
+	authHeader := r.Header.Get("Authorization")
+	numWeeks := getWeeks(r)
+	since := getStartDate(numWeeks)
+	org := params.ByName("org")
+
+	members, orgID, err := getTeamMembers(client, authHeader, org, params.ByName("team"))
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	memberPRs := make([][]pr, len(members))
+	memberReviews := make([][]review, len(members))
+	err = forEachParallel(len(members), maxParallelism, func(i int) error {
+		prs, err := getUserPRsCreatedSince(client, authHeader, members[i], since)
+		if err != nil {
+			return errors.Wrap(err, members[i])
+		}
+		reviews, err := getUserReviewsSince(client, authHeader, members[i], orgID, since)
+		memberPRs[i], memberReviews[i] = prs, reviews
+		return errors.Wrap(err, members[i])
+	})
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	teamScore := GetTeamPRScore(org, members, memberPRs, memberReviews, since, numWeeks)
+	writeResponse(w, r, teamScore)
+}
+
+func CompareRepositories(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	client := graphql.NewClient("https://api.github.com/graphql")
+
+	authHeader := r.Header.Get("Authorization")
+	numWeeks := getWeeks(r)
+	since := getStartDate(numWeeks)
+	filter, err := getPRFilter(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	repos := getListParam(r, "repos")
+	if len(repos) == 0 {
+		log.Println("no repos to compare")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, repo := range repos {
+		if _, _, ok := splitRepo(repo); !ok {
+			log.Println("invalid repo, expected owner/name", repo)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	data := make([]repoData, len(repos))
+	err = forEachParallel(len(repos), maxParallelism, func(i int) error {
+		owner, name, _ := splitRepo(repos[i])
+		repo, err := getRepoData(client, authHeader, owner, name, since, filter)
+		data[i] = repo
+		return errors.Wrap(err, repos[i])
+	})
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	comparison := GetRepoComparison(data, since, numWeeks)
+	writeResponse(w, r, comparison)
+}
+
+func GetRepositoryHealth(config *Config) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		client := graphql.NewClient("https://api.github.com/graphql")
+
+		authHeader := r.Header.Get("Authorization")
+		numWeeks := getWeeks(r)
+		since := getStartDate(numWeeks)
+		filter, err := getPRFilter(r)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		repo, err := getRepoData(client, authHeader, params.ByName("owner"), params.ByName("name"), since, filter)
+		if err != nil {
+			handleError(err, w)
+			return
+		}
+		health := GetHealthScore(
+			config.Health,
+			GetIssueScore(repo.Issues, repo.NumOpen, since, numWeeks, false),
+			GetPRScore(repo.PRs, since, numWeeks),
+			GetCIScore(repo.CIPRs, since, numWeeks),
+		)
+		writeResponse(w, r, health)
+	}
+}
+
+func GetRepositorySLOs(config *Config) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		client := graphql.NewClient("https://api.github.com/graphql")
+
+		authHeader := r.Header.Get("Authorization")
+		filter, err := getPRFilter(r)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		maxWindow := 0
+		for _, slo := range config.SLOs {
+			if slo.Window > maxWindow {
+				maxWindow = slo.Window
+			}
+		}
+		since := now.AddDate(0, 0, -7*maxWindow)
+
+		repo, err := getRepoData(client, authHeader, params.ByName("owner"), params.ByName("name"), since, filter)
+		if err != nil {
+			handleError(err, w)
+			return
+		}
+		reports := []SLOReport{}
+		for _, slo := range config.SLOs {
+			reports = append(reports, EvaluateSLO(slo, repo, now))
+		}
+		writeResponse(w, r, reports)
+	}
+}
+
+func GetReport(config *Config) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		authHeader := r.Header.Get("Authorization")
+		repos := getListParam(r, "repos")
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = ReportFormatHTML
+		}
+		if len(repos) == 0 || format != ReportFormatHTML && format != ReportFormatMarkdown {
+			log.Println("invalid report request", repos, format)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, repo := range repos {
+			if _, _, ok := splitRepo(repo); !ok {
+				log.Println("invalid repo, expected owner/name", repo)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		report, err := BuildReport(authHeader, repos, time.Now())
+		if err != nil {
+			handleError(err, w)
+			return
+		}
+		if format == ReportFormatHTML {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		}
+		if err := WriteReport(w, report, format, config.Report); err != nil {
+			log.Println(err)
+		}
+	}
 }
@@ -0,0 +1,186 @@
+package repohealth
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	sloMetricPRFirstReview      = "prFirstReview"      // PR opened to first review by someone else
+	sloMetricPRResolution       = "prResolution"       // PR opened to merged or closed
+	sloMetricIssueFirstResponse = "issueFirstResponse" // issue opened to first maintainer comment
+	sloMetricIssueResolution    = "issueResolution"    // issue opened to closed
+	sloMetricCIDuration         = "ciDuration"         // push to slowest check finishing, for each PR's latest commit
+)
+
+// something measured by an SLO. End is zero if it has not happened yet
+type sloSample struct {
+	Number int
+	URL    string
+	Start  time.Time
+	End    time.Time
+}
+
+var sloMetrics = map[string]func(repo repoData) []sloSample{
+	sloMetricPRFirstReview: func(repo repoData) []sloSample {
+		var samples []sloSample
+		for _, pr := range repo.PRs {
+			sample := sloSample{Number: pr.Number, URL: pr.URL, Start: pr.CreatedAt}
+			for _, review := range pr.Reviews.Nodes {
+				if review.Author.Login != pr.Author.Login {
+					sample.End = review.CreatedAt
+					break
+				}
+			}
+			samples = append(samples, sample)
+		}
+		return samples
+	},
+	sloMetricPRResolution: func(repo repoData) []sloSample {
+		var samples []sloSample
+		for _, pr := range repo.PRs {
+			samples = append(samples, sloSample{Number: pr.Number, URL: pr.URL, Start: pr.CreatedAt, End: pr.ClosedAt})
+		}
+		return samples
+	},
+	sloMetricIssueFirstResponse: func(repo repoData) []sloSample {
+		var samples []sloSample
+		for _, issue := range repo.Issues {
+			sample := sloSample{Number: issue.Number, URL: issue.URL, Start: issue.CreatedAt}
+			if response := getFirstMaintainerResponse(issue); response != nil {
+				sample.End = response.CreatedAt
+			}
+			samples = append(samples, sample)
+		}
+		return samples
+	},
+	sloMetricIssueResolution: func(repo repoData) []sloSample {
+		var samples []sloSample
+		for _, issue := range repo.Issues {
+			sample := sloSample{Number: issue.Number, URL: issue.URL, Start: issue.CreatedAt}
+			if issue.State == "CLOSED" {
+				sample.End = issue.ClosedAt
+			}
+			samples = append(samples, sample)
+		}
+		return samples
+	},
+	sloMetricCIDuration: func(repo repoData) []sloSample {
+		var samples []sloSample
+		for _, pr := range repo.CIPRs {
+			if len(pr.Commits.Nodes) == 0 || len(pr.Commits.Nodes[0].Commit.Status.Contexts) == 0 {
+				continue
+			}
+			statusStartDate := getStatusStartDate(pr)
+			_, maxCheckDuration := getMaxCheck(pr, statusStartDate)
+			samples = append(samples, sloSample{
+				Number: pr.Number,
+				URL:    pr.URL,
+				Start:  statusStartDate,
+				End:    statusStartDate.Add(time.Duration(maxCheckDuration) * time.Second),
+			})
+		}
+		return samples
+	},
+}
+
+type SLOReport struct {
+	Name       string  `json:"name"`
+	Metric     string  `json:"metric"`
+	Threshold  int     `json:"threshold"`  // in sec
+	Target     float64 `json:"target"`     // percentage of items that must meet the threshold
+	Window     int     `json:"window"`     // in weeks
+	Compliance float64 `json:"compliance"` // percentage of items that met the threshold, -1 if there were none
+	// percentage of the allowed violations that are left, negative once the budget is exceeded
+	ErrorBudgetRemaining float64        `json:"errorBudgetRemaining"`
+	Observed             int            `json:"observed"` // in sec, the target percentile of durations, -1 if none
+	NumMet               int            `json:"met"`
+	NumViolated          int            `json:"violated"`
+	Violations           []SLOViolation `json:"violations"`
+}
+
+type SLOViolation struct {
+	Number   int    `json:"number"`
+	URL      string `json:"url"`
+	Duration int    `json:"duration"` // in sec, so far if the item is still pending
+	Pending  bool   `json:"pending"`
+}
+
+// repo should include everything since the start of the SLO's window. items that haven't finished are only counted
+// once they have exceeded the threshold
+func EvaluateSLO(slo SLOConfig, repo repoData, now time.Time) SLOReport {
+	report := SLOReport{
+		Name:       slo.Name,
+		Metric:     slo.Metric,
+		Threshold:  int(slo.Threshold.Seconds()),
+		Target:     slo.Percentile,
+		Window:     slo.Window,
+		Violations: []SLOViolation{},
+	}
+
+	windowStart := now.AddDate(0, 0, -7*slo.Window)
+	var durations []int
+	for _, sample := range sloMetrics[slo.Metric](repo) {
+		if sample.Start.Before(windowStart) {
+			continue
+		}
+		end := sample.End
+		if end.IsZero() {
+			end = now
+		}
+		duration := end.Sub(sample.Start)
+		if slo.BusinessTime {
+			duration = businessDuration(sample.Start, end)
+		}
+
+		if duration > slo.Threshold.Duration {
+			report.NumViolated++
+			report.Violations = append(report.Violations, SLOViolation{
+				Number:   sample.Number,
+				URL:      sample.URL,
+				Duration: int(duration.Seconds()),
+				Pending:  sample.End.IsZero(),
+			})
+		} else if !sample.End.IsZero() {
+			report.NumMet++
+		} else {
+			continue
+		}
+		durations = append(durations, int(duration.Seconds()))
+	}
+
+	total := report.NumMet + report.NumViolated
+	report.Compliance = -1
+	if total > 0 {
+		report.Compliance = float64(report.NumMet) / float64(total) * 100
+	}
+	allowed := (100 - slo.Percentile) / 100 * float64(total)
+	switch {
+	case allowed > 0:
+		report.ErrorBudgetRemaining = (allowed - float64(report.NumViolated)) / allowed * 100
+	case report.NumViolated == 0:
+		report.ErrorBudgetRemaining = 100
+	}
+	report.Observed = percentile(durations, slo.Percentile)
+
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		return report.Violations[i].Duration > report.Violations[j].Duration
+	})
+	return report
+}
+
+// returns the time between start and end, not counting Saturdays and Sundays
+func businessDuration(start time.Time, end time.Time) time.Duration {
+	var duration time.Duration
+	for t := start; t.Before(end); {
+		next := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		if next.After(end) {
+			next = end
+		}
+		if weekday := t.Weekday(); weekday != time.Saturday && weekday != time.Sunday {
+			duration += next.Sub(t)
+		}
+		t = next
+	}
+	return duration
+}
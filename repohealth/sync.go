@@ -0,0 +1,125 @@
+package repohealth
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/pkg/errors"
+)
+
+// the data last fetched for a repo by the Syncer
+type repoSnapshot struct {
+	Data       repoData
+	NumOpenPRs int
+	Since      time.Time
+	NumWeeks   int
+	SyncedAt   time.Time
+}
+
+func (s repoSnapshot) metrics() ([]WeeklyIssueMetrics, []WeeklyPRMetrics, []WeeklyCIMetrics) {
+	return GetIssueScore(s.Data.Issues, s.Data.NumOpen, s.Since, s.NumWeeks, false),
+		GetPRScore(s.Data.PRs, s.Since, s.NumWeeks),
+		GetCIScore(s.Data.CIPRs, s.Since, s.NumWeeks)
+}
+
+// periodically fetches the configured repos in the background, so that alerts and exporters don't need a request's
+// credentials or have to wait on GitHub
+type Syncer struct {
+	config     SyncConfig
+	client     *graphql.Client
+	authHeader string
+
+	mu        sync.RWMutex
+	snapshots map[string]repoSnapshot
+	listeners []func(snapshots map[string]repoSnapshot)
+}
+
+func NewSyncer(config SyncConfig, token string) *Syncer {
+	return &Syncer{
+		config:     config,
+		client:     graphql.NewClient(config.Endpoint),
+		authHeader: "bearer " + token,
+		snapshots:  map[string]repoSnapshot{},
+	}
+}
+
+// registers fn to be called with every repo's latest snapshot after each sync. must be called before Run
+func (s *Syncer) OnSync(fn func(snapshots map[string]repoSnapshot)) {
+	s.listeners = append(s.listeners, fn)
+}
+
+// syncs immediately and then on every interval until stop is closed
+func (s *Syncer) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.config.Interval.Duration)
+	defer ticker.Stop()
+	for {
+		s.sync()
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Syncer) sync() {
+	since := getStartDate(s.config.Weeks)
+	repos := s.config.Repos
+	snapshots := make([]*repoSnapshot, len(repos))
+	forEachParallel(len(repos), maxParallelism, func(i int) error {
+		owner, name, _ := splitRepo(repos[i])
+		data, err := getRepoData(s.client, s.authHeader, owner, name, since, prFilter{})
+		if err != nil {
+			// keep serving the previous snapshot
+			log.Println(errors.Wrapf(err, "failed to sync %s", repos[i]))
+			return nil
+		}
+		numOpenPRs, err := getOpenPRCount(s.client, s.authHeader, owner, name)
+		if err != nil {
+			log.Println(errors.Wrapf(err, "failed to sync %s", repos[i]))
+			return nil
+		}
+		snapshots[i] = &repoSnapshot{
+			Data:       data,
+			NumOpenPRs: numOpenPRs,
+			Since:      since,
+			NumWeeks:   s.config.Weeks,
+			SyncedAt:   time.Now(),
+		}
+		return nil
+	})
+
+	s.mu.Lock()
+	for i, snapshot := range snapshots {
+		if snapshot != nil {
+			s.snapshots[repos[i]] = *snapshot
+		}
+	}
+	s.mu.Unlock()
+
+	latest := s.getSnapshots()
+	for _, listener := range s.listeners {
+		listener(latest)
+	}
+}
+
+// returns a copy of the latest snapshots by owner/name
+func (s *Syncer) getSnapshots() map[string]repoSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshots := map[string]repoSnapshot{}
+	for repo, snapshot := range s.snapshots {
+		snapshots[repo] = snapshot
+	}
+	return snapshots
+}
+
+// returns the latest snapshot of the repo (owner/name), ok is false if it isn't synced or hasn't been fetched yet
+func (s *Syncer) getSnapshot(repo string) (snapshot repoSnapshot, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok = s.snapshots[repo]
+	return snapshot, ok
+}
@@ -0,0 +1,82 @@
+package repohealth
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type UserProfile struct {
+	PRs     []WeeklyPRMetrics     `json:"prs"`     // authored by the user
+	Reviews []WeeklyReviewMetrics `json:"reviews"` // given by the user
+	Issues  []WeeklyIssueMetrics  `json:"issues"`  // opened by the user
+	Repos   []UserRepoActivity    `json:"repos"`
+}
+
+// totals over the whole window
+type UserRepoActivity struct {
+	Repo            string `json:"repo"`
+	NumPRsOpened    int    `json:"prsOpened"`
+	NumPRsMerged    int    `json:"prsMerged"`
+	NumReviews      int    `json:"reviews"`
+	NumIssuesOpened int    `json:"issuesOpened"`
+	NumIssuesClosed int    `json:"issuesClosed"`
+}
+
+// if org is not empty, only PRs and issues in that org's repos are included. reviews are expected to already be
+// scoped to the org
+func GetUserScore(org string, prs []pr, reviews []review, issues []issue, since time.Time, numWeeks int) UserProfile {
+	repoToActivity := map[string]*UserRepoActivity{}
+	activity := func(repo string) *UserRepoActivity {
+		if repoToActivity[repo] == nil {
+			repoToActivity[repo] = &UserRepoActivity{Repo: repo}
+		}
+		return repoToActivity[repo]
+	}
+
+	var orgPRs []pr
+	for _, pr := range prs {
+		if org != "" && !strings.EqualFold(pr.Repository.Owner.Login, org) {
+			continue
+		}
+		orgPRs = append(orgPRs, pr)
+		activity(pr.Repository.NameWithOwner).NumPRsOpened++
+		if pr.Merged {
+			activity(pr.Repository.NameWithOwner).NumPRsMerged++
+		}
+	}
+
+	var orgIssues []issue
+	for _, issue := range issues {
+		if org != "" && !strings.EqualFold(issue.Repository.Owner.Login, org) {
+			continue
+		}
+		orgIssues = append(orgIssues, issue)
+		activity(issue.Repository.NameWithOwner).NumIssuesOpened++
+		if issue.State == "CLOSED" {
+			activity(issue.Repository.NameWithOwner).NumIssuesClosed++
+		}
+	}
+
+	for _, review := range reviews {
+		if !review.OccurredAt.Before(since) {
+			activity(review.PullRequest.Repository.NameWithOwner).NumReviews++
+		}
+	}
+
+	repos := []UserRepoActivity{}
+	for _, activity := range repoToActivity {
+		repos = append(repos, *activity)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Repo < repos[j].Repo
+	})
+
+	return UserProfile{
+		PRs:     GetPRScore(orgPRs, since, numWeeks),
+		Reviews: GetReviewScore(reviews, since, numWeeks),
+		// the backlog is unknown since only issues opened in the window are fetched
+		Issues: GetIssueScore(orgIssues, -1, since, numWeeks, false),
+		Repos:  repos,
+	}
+}